@@ -0,0 +1,156 @@
+package gem
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/crazyfrankie/gem/binding"
+)
+
+// bindSources reports which request parts a type declares tags for.
+type bindSources struct {
+	uri, query, header, cookie, form bool
+}
+
+// bindSourcesCache maps a struct type to its bindSources.
+var bindSourcesCache sync.Map
+
+func cachedSources(t reflect.Type) bindSources {
+	if s, ok := bindSourcesCache.Load(t); ok {
+		return s.(bindSources)
+	}
+	s, _ := bindSourcesCache.LoadOrStore(t, tagSources(t, make(map[reflect.Type]bool)))
+	return s.(bindSources)
+}
+
+// tagSources collects the tags of t, descending into nested and embedded structs.
+func tagSources(t reflect.Type, seen map[reflect.Type]bool) (s bindSources) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return s
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		_, uri := field.Tag.Lookup("uri")
+		_, query := field.Tag.Lookup("query")
+		_, header := field.Tag.Lookup("header")
+		_, cookie := field.Tag.Lookup("cookie")
+		_, form := field.Tag.Lookup("form")
+		nested := tagSources(field.Type, seen)
+		s.uri = s.uri || uri || nested.uri
+		s.query = s.query || query || nested.query
+		s.header = s.header || header || nested.header
+		s.cookie = s.cookie || cookie || nested.cookie
+		s.form = s.form || form || nested.form
+	}
+	return s
+}
+
+// BindAll calls ShouldBindAll and aborts the request through RenderError if it fails,
+// see DefaultErrorHandler for the response.
+func (c *Context) BindAll(obj any) error {
+	if err := c.ShouldBindAll(obj); err != nil {
+		c.RenderError(bindingError(err))
+		return err
+	}
+
+	return nil
+}
+
+// ShouldBindAll fills obj from every request part it declares tags for, then validates it once.
+// The sources are bound from the lowest precedence to the highest, so a field tagged
+// for several sources keeps the value of the last one present:
+//
+//	body < cookie < header < query < uri
+//
+// The body is bound with the binding selected by Content-Type, GET and HEAD requests
+// bind the form tags from the query string instead. The default= option of a field only
+// applies when no source supplied it, see binding.MapSources. The Source of every
+// binding.Error names the request part the failing field is bound from.
+func (c *Context) ShouldBindAll(obj any) error {
+	t := reflect.TypeOf(obj)
+	sources := cachedSources(t)
+
+	var values []binding.Values
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead:
+		if sources.form {
+			values = append(values, binding.Values{Tag: binding.SourceForm, Values: c.Request.URL.Query()})
+		}
+	default:
+		if c.Request.ContentLength != 0 && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			b, err := c.defaultBinding()
+			if err != nil {
+				return err
+			}
+			if err := c.bindWith(obj, b); err != nil {
+				return err
+			}
+		}
+	}
+	if sources.cookie {
+		cookies := c.Request.Cookies()
+		m := make(map[string][]string, len(cookies))
+		for _, cookie := range cookies {
+			m[cookie.Name] = append(m[cookie.Name], cookie.Value)
+		}
+		values = append(values, binding.Values{Tag: binding.SourceCookie, Values: m})
+	}
+	if sources.header {
+		values = append(values, binding.Values{Tag: binding.SourceHeader, Values: c.Request.Header})
+	}
+	if sources.query {
+		values = append(values, binding.Values{Tag: binding.SourceQuery, Values: c.Request.URL.Query()})
+	}
+	if sources.uri {
+		m := make(map[string][]string, len(c.Params))
+		for _, v := range c.Params {
+			m[v.Key] = []string{v.Value}
+		}
+		values = append(values, binding.Values{Tag: binding.SourceURI, Values: m})
+	}
+	if err := binding.MapSources(obj, values...); err != nil {
+		return err
+	}
+
+	err := binding.Validate(obj)
+	if errs, ok := err.(binding.Errors); ok {
+		for _, e := range errs {
+			e.Source = fieldSource(t, e.Field)
+		}
+	}
+	return err
+}
+
+// fieldSource returns the source of the field at path, such as "Items[0].Name",
+// from the tags met along the path. Fields without a source tag come from the body.
+func fieldSource(t reflect.Type, path string) string {
+	for _, name := range strings.Split(path, ".") {
+		name, _, _ = strings.Cut(name, "[")
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			break
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			break
+		}
+		for _, source := range []string{binding.SourceURI, binding.SourceQuery, binding.SourceHeader, binding.SourceCookie, binding.SourceForm} {
+			if _, ok := field.Tag.Lookup(source); ok {
+				return source
+			}
+		}
+		t = field.Type
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+	}
+	return binding.SourceBody
+}
@@ -0,0 +1,77 @@
+package gem
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crazyfrankie/gem/binding"
+)
+
+type bindAllRequest struct {
+	ID      int    `uri:"id" json:"id"`
+	Page    int    `query:"page,default=1" json:"page"`
+	Size    int    `query:"size,default=20" json:"size"`
+	Token   string `header:"X-Token" json:"-" validate:"required"`
+	Session string `cookie:"session" json:"-"`
+	Name    string `json:"name" validate:"required"`
+	Lang    string `query:"lang" cookie:"lang" json:"lang" validate:"oneof=en fr"`
+}
+
+func TestBindAll(t *testing.T) {
+	server := New()
+	var got bindAllRequest
+	var bindErr error
+	server.POST("/users/:id", func(c *Context) {
+		got = bindAllRequest{}
+		bindErr = c.ShouldBindAll(&got)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users/7?lang=fr", strings.NewReader(`{"id":1,"name":"gem","lang":"de","page":5}`))
+	req.Header.Set("Content-Type", binding.MIMEJSON)
+	req.Header.Set("X-Token", "t")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s"})
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "en"})
+	server.ServeHTTP(httptest.NewRecorder(), req)
+	if bindErr != nil {
+		t.Fatal(bindErr)
+	}
+	want := bindAllRequest{ID: 7, Page: 5, Size: 20, Token: "t", Session: "s", Name: "gem", Lang: "fr"}
+	if got != want {
+		t.Errorf("bound %+v, want %+v", got, want)
+	}
+
+	// An explicit zero from a higher source is kept rather than replaced by the default.
+	req = httptest.NewRequest(http.MethodPost, "/users/7?page=0&lang=en", strings.NewReader(`{"name":"gem","page":5}`))
+	req.Header.Set("Content-Type", binding.MIMEJSON)
+	req.Header.Set("X-Token", "t")
+	server.ServeHTTP(httptest.NewRecorder(), req)
+	if bindErr != nil {
+		t.Fatal(bindErr)
+	}
+	if got.Page != 0 || got.Size != 20 {
+		t.Errorf("page = %d, size = %d, want 0 and 20", got.Page, got.Size)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users/7", strings.NewReader(`{"lang":"de"}`))
+	req.Header.Set("Content-Type", binding.MIMEJSON)
+	server.ServeHTTP(httptest.NewRecorder(), req)
+	var errs binding.Errors
+	if !errors.As(bindErr, &errs) || len(errs) != 3 {
+		t.Fatalf("errors = %v", bindErr)
+	}
+	for i, source := range []string{binding.SourceHeader, binding.SourceBody, binding.SourceQuery} {
+		if errs[i].Source != source {
+			t.Errorf("%s source = %q, want %q", errs[i].Field, errs[i].Source, source)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/users/x", nil)
+	server.ServeHTTP(httptest.NewRecorder(), req)
+	var e *binding.Error
+	if !errors.As(bindErr, &e) || e.Source != binding.SourceURI || e.Field != "id" {
+		t.Errorf("uri error = %v", bindErr)
+	}
+}
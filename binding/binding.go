@@ -1,6 +1,10 @@
 package binding
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/crazyfrankie/gem/codec"
+)
 
 type Binding interface {
 	Name() string
@@ -18,11 +22,59 @@ type BindingUri interface {
 }
 
 var (
-	JSON   BindingBody = jsonBinding{}
-	PLAIN  BindingBody = plainBinding{}
-	YAML   BindingBody = yamlBinding{}
-	XML    BindingBody = xmlBinding{}
-	Query  Binding     = queryBinding{}
-	Header Binding     = headerBinding{}
-	Uri    BindingUri  = uriBinding{}
+	JSON          BindingBody = jsonBinding{}
+	PLAIN         BindingBody = plainBinding{}
+	YAML          BindingBody = yamlBinding{}
+	XML           BindingBody = xmlBinding{}
+	ProtoBuf      BindingBody = protobufBinding{}
+	MsgPack       BindingBody = msgpackBinding{}
+	CBOR          BindingBody = cborBinding{}
+	Form          Binding     = formBinding{}
+	FormPost      Binding     = formPostBinding{}
+	FormMultipart Binding     = formMultipartBinding{}
+	Query         Binding     = queryBinding{}
+	Header        Binding     = headerBinding{}
+	Cookie        Binding     = cookieBinding{}
+	Uri           BindingUri  = uriBinding{}
+)
+
+// Content-Type MIME of the most common data formats.
+const (
+	MIMEJSON              = "application/json"
+	MIMEHTML              = "text/html"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPlain             = "text/plain"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+	MIMEPROTOBUF          = "application/x-protobuf"
+	MIMEYAML              = "application/x-yaml"
+	MIMEYAML2             = "application/yaml"
+	MIMEMSGPACK           = "application/msgpack"
+	MIMEMSGPACK2          = "application/x-msgpack"
+	MIMECBOR              = "application/cbor"
 )
+
+// WithCodec returns the JSON, XML or YAML binding b decoding with c, unless b already has
+// a codec, keeping options such as those set by JSONWith. Other bindings are returned unchanged.
+func WithCodec(b BindingBody, c codec.Codec) BindingBody {
+	switch b := b.(type) {
+	case jsonBinding:
+		b.codec = codecOr(b.codec, c)
+		return b
+	case xmlBinding:
+		b.codec = codecOr(b.codec, c)
+		return b
+	case yamlBinding:
+		b.codec = codecOr(b.codec, c)
+		return b
+	}
+	return b
+}
+
+func codecOr(c, def codec.Codec) codec.Codec {
+	if c == nil {
+		return def
+	}
+	return c
+}
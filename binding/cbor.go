@@ -0,0 +1,27 @@
+package binding
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/crazyfrankie/gem/internal/cbor"
+)
+
+type cborBinding struct{}
+
+func (cborBinding) Name() string {
+	return "cbor"
+}
+
+func (b cborBinding) Bind(req *http.Request, obj any) error {
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		return bodyError(err)
+	}
+
+	return b.BindBody(buf, obj)
+}
+
+func (cborBinding) BindBody(body []byte, obj any) error {
+	return bodyError(cbor.Unmarshal(body, obj))
+}
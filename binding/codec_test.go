@@ -0,0 +1,55 @@
+package binding
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/crazyfrankie/gem/internal/cbor"
+	"github.com/crazyfrankie/gem/internal/msgpack"
+)
+
+func TestJSONBindingProtoMessage(t *testing.T) {
+	// structpb.Value has a protojson mapping encoding/json can not decode.
+	msg := new(structpb.Value)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"name":"gem","tags":["a"]}`))
+	if err := JSON.Bind(req, msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.GetStructValue().GetFields()["name"].GetStringValue(); got != "gem" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestBinaryBindings(t *testing.T) {
+	type payload struct {
+		Name string `msgpack:"name" cbor:"name" validate:"required"`
+		Age  int    `msgpack:"age" cbor:"age"`
+	}
+	in := payload{Name: "gem", Age: 3}
+	mp, _ := msgpack.Marshal(in)
+	cb, _ := cbor.Marshal(in)
+
+	tests := []struct {
+		contentType string
+		body        []byte
+	}{
+		{MIMEMSGPACK, mp},
+		{MIMEMSGPACK2, mp},
+		{MIMECBOR, cb},
+	}
+	for _, tt := range tests {
+		b := Default(http.MethodPost, tt.contentType)
+		if b == nil {
+			t.Fatalf("%s is not registered", tt.contentType)
+		}
+		var out payload
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+		if err := b.Bind(req, &out); err != nil || out != in {
+			t.Errorf("%s: got %+v, %v", tt.contentType, out, err)
+		}
+	}
+}
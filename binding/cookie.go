@@ -0,0 +1,18 @@
+package binding
+
+import "net/http"
+
+type cookieBinding struct{}
+
+func (cookieBinding) Name() string {
+	return "cookie"
+}
+
+func (cookieBinding) Bind(req *http.Request, obj any) error {
+	cookies := req.Cookies()
+	m := make(map[string][]string, len(cookies))
+	for _, c := range cookies {
+		m[c.Name] = append(m[c.Name], c.Value)
+	}
+	return mapFormByTag(obj, m, "cookie")
+}
@@ -0,0 +1,92 @@
+package binding
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// BindUnmarshaler is implemented by types that parse themselves from a single
+// form, query, header or uri value. It takes precedence over encoding.TextUnmarshaler.
+type BindUnmarshaler interface {
+	UnmarshalParam(param string) error
+}
+
+var bindUnmarshalerType = reflect.TypeOf((*BindUnmarshaler)(nil)).Elem()
+
+// decoders maps a reflect.Type to the func(string, reflect.Value) error registered for it.
+var decoders sync.Map
+
+// RegisterDecoder registers fn to parse the form, query, header and uri values bound to T,
+// such as a Money or ULID type. A registered decoder takes precedence over BindUnmarshaler
+// and encoding.TextUnmarshaler, registering T again replaces its decoder.
+func RegisterDecoder[T any](fn func(string) (T, error)) {
+	if fn == nil {
+		panic("binding: decoder can not be nil")
+	}
+	decoders.Store(reflect.TypeOf((*T)(nil)).Elem(), func(val string, value reflect.Value) error {
+		v, err := fn(val)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(&v).Elem())
+		return nil
+	})
+}
+
+// hasDecoder reports whether values of value's type are parsed by a registered decoder,
+// a BindUnmarshaler or an encoding.TextUnmarshaler rather than by kind.
+func hasDecoder(value reflect.Value) bool {
+	if _, ok := decoders.Load(value.Type()); ok {
+		return true
+	}
+	return value.CanAddr() && value.Addr().Type().Implements(bindUnmarshalerType) || isTextUnmarshaler(value)
+}
+
+// decodeCustom sets value from val with the registered decoder of its type or its
+// BindUnmarshaler implementation, it reports false when neither applies.
+func decodeCustom(val string, value reflect.Value) (bool, error) {
+	if dec, ok := decoders.Load(value.Type()); ok {
+		return true, dec.(func(string, reflect.Value) error)(val, value)
+	}
+	if value.CanAddr() && value.Addr().Type().Implements(bindUnmarshalerType) {
+		return true, value.Addr().Interface().(BindUnmarshaler).UnmarshalParam(val)
+	}
+	return false, nil
+}
+
+// parsers split every value of a slice or array field tagged `parser:"name"`,
+// so `query:"ids" parser:"csv"` binds ?ids=1,2&ids=3 as [1 2 3].
+var parsers = map[string]string{
+	"csv":   ",",
+	"ssv":   " ",
+	"tsv":   "\t",
+	"pipes": "|",
+}
+
+// parserSep returns the separator of the parser tag of field.
+func parserSep(field reflect.StructField) (string, error) {
+	name, ok := field.Tag.Lookup("parser")
+	if !ok {
+		return "", nil
+	}
+	sep, ok := parsers[name]
+	if !ok {
+		return "", fmt.Errorf("unknown parser %q", name)
+	}
+	return sep, nil
+}
+
+// splitValues splits every value of vs around sep, empty parts are dropped.
+func splitValues(vs []string, sep string) []string {
+	out := make([]string, 0, len(vs))
+	for _, v := range vs {
+		for _, s := range strings.Split(v, sep) {
+			if s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
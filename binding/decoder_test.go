@@ -0,0 +1,85 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type money struct {
+	Cents    int64
+	Currency string
+}
+
+type level int
+
+func (l *level) UnmarshalParam(param string) error {
+	switch param {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", param)
+	}
+	return nil
+}
+
+func init() {
+	RegisterDecoder(func(s string) (money, error) {
+		amount, currency, ok := strings.Cut(s, " ")
+		if !ok {
+			return money{}, errors.New("missing currency")
+		}
+		cents, err := strconv.ParseInt(amount, 10, 64)
+		return money{Cents: cents, Currency: currency}, err
+	})
+}
+
+func TestCustomDecoders(t *testing.T) {
+	var got struct {
+		Price  money   `query:"price"`
+		Max    *money  `query:"max"`
+		Level  level   `query:"level"`
+		Levels []level `query:"levels" parser:"csv"`
+		IDs    []int   `query:"id" parser:"csv"`
+		Pair   [2]int  `query:"pair" parser:"pipes"`
+		Limit  *level  `uri:"limit"`
+	}
+
+	req := httptest.NewRequest("GET", "/?price=150+EUR&max=900+USD&level=high&levels=low,high&id=1,2&id=3&pair=4|5", nil)
+	if err := Query.Bind(req, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := Uri.BindingUri(map[string][]string{"limit": {"low"}}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Price != (money{150, "EUR"}) || *got.Max != (money{900, "USD"}) || got.Level != 2 || *got.Limit != 1 {
+		t.Errorf("decoded %+v", got)
+	}
+	if !reflect.DeepEqual(got.Levels, []level{1, 2}) || !reflect.DeepEqual(got.IDs, []int{1, 2, 3}) || got.Pair != [2]int{4, 5} {
+		t.Errorf("parsed %+v", got)
+	}
+
+	err := Query.Bind(httptest.NewRequest("GET", "/?level=mid", nil), &got)
+	var e *Error
+	if !errors.As(err, &e) || e.Field != "level" || e.Value != "mid" {
+		t.Errorf("level error = %v", err)
+	}
+}
+
+func TestUnknownParser(t *testing.T) {
+	var obj struct {
+		IDs []int `query:"ids" parser:"semicolons"`
+	}
+	for range 2 {
+		err := Query.Bind(httptest.NewRequest("GET", "/?ids=1;2", nil), &obj)
+		if !errors.Is(err, ErrInvalidTag) || !strings.Contains(err.Error(), `unknown parser "semicolons"`) {
+			t.Errorf("unknown parser: %v", err)
+		}
+	}
+}
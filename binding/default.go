@@ -0,0 +1,66 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedMediaType is returned when no binding is registered for the request Content-Type.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// ErrBodyTooLarge is returned when the request body exceeds the size limit of the server
+// or route, or the limits of its decompressed size.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+var bodyBindings = struct {
+	sync.RWMutex
+	m map[string]Binding
+}{m: map[string]Binding{
+	MIMEJSON:              JSON,
+	MIMEXML:               XML,
+	MIMEXML2:              XML,
+	MIMEYAML:              YAML,
+	MIMEYAML2:             YAML,
+	MIMEPROTOBUF:          ProtoBuf,
+	MIMEPlain:             PLAIN,
+	MIMEMSGPACK:           MsgPack,
+	MIMEMSGPACK2:          MsgPack,
+	MIMECBOR:              CBOR,
+	MIMEPOSTForm:          Form,
+	MIMEMultipartPOSTForm: FormMultipart,
+}}
+
+// Register makes b the binding Default selects for the media type mime.
+// Registering an already known media type replaces its binding.
+func Register(mime string, b Binding) {
+	if b == nil {
+		panic("binding: Register binding is nil")
+	}
+	bodyBindings.Lock()
+	defer bodyBindings.Unlock()
+	bodyBindings.m[mediaType(mime)] = b
+}
+
+// Default returns the appropriate Binding instance based on the HTTP method
+// and the content type. GET requests and requests without a body type bind the form,
+// nil is returned when no binding is registered for contentType.
+func Default(method, contentType string) Binding {
+	mime := mediaType(contentType)
+	if method == http.MethodGet || mime == "" {
+		return Form
+	}
+
+	bodyBindings.RLock()
+	defer bodyBindings.RUnlock()
+	return bodyBindings.m[mime]
+}
+
+// mediaType returns the lower-cased type/subtype of a Content-Type value.
+func mediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
@@ -0,0 +1,37 @@
+package binding
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		method, contentType string
+		want                Binding
+	}{
+		{http.MethodGet, MIMEJSON, Form},
+		{http.MethodPost, "", Form},
+		{http.MethodPost, "Application/JSON; charset=utf-8", JSON},
+		{http.MethodPut, MIMEXML2, XML},
+		{http.MethodPatch, MIMEYAML2, YAML},
+		{http.MethodPost, MIMEPOSTForm, Form},
+		{http.MethodPost, MIMEMultipartPOSTForm + "; boundary=x", FormMultipart},
+		{http.MethodPost, "text/csv", nil},
+	}
+	for _, tt := range tests {
+		if got := Default(tt.method, tt.contentType); got != tt.want {
+			t.Errorf("Default(%s, %q) = %v, want %v", tt.method, tt.contentType, got, tt.want)
+		}
+	}
+
+	Register("text/csv", PLAIN)
+	defer func() {
+		bodyBindings.Lock()
+		delete(bodyBindings.m, "text/csv")
+		bodyBindings.Unlock()
+	}()
+	if got := Default(http.MethodPost, "text/csv"); got != PLAIN {
+		t.Errorf("registered text/csv: %v", got)
+	}
+}
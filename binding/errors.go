@@ -0,0 +1,144 @@
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sources of the values reported by Error.
+const (
+	SourceBody   = "body"
+	SourceForm   = "form"
+	SourceQuery  = "query"
+	SourceHeader = "header"
+	SourceCookie = "cookie"
+	SourceURI    = "uri"
+)
+
+// Error describes a value of the request that could not be bound or validated.
+type Error struct {
+	// Source is where the value comes from, one of the Source constants.
+	// It is empty for validation failures reported by Validate itself.
+	Source string
+	// Field locates the value: the key of form, query, header and uri values,
+	// the JSON pointer of JSON bodies, or the struct field path of validation failures.
+	Field string
+	// Type is the Go type the value was bound to.
+	Type string
+	// Value is the offending value, when it is known.
+	Value any
+	Err   error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("binding")
+	if e.Source != "" {
+		b.WriteString(" " + e.Source)
+	}
+	if e.Field != "" {
+		b.WriteString(" field " + e.Field)
+	}
+	if e.Type != "" {
+		b.WriteString(" (" + e.Type + ")")
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors lists the failures of a bind, such as every violation found by validation.
+type Errors []*Error
+
+func (es Errors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (es Errors) Unwrap() []error {
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = e
+	}
+	return errs
+}
+
+// As lets errors.As find the ValidationErrors that Errors was built from.
+func (es Errors) As(target any) bool {
+	ve, ok := target.(*ValidationErrors)
+	if !ok {
+		return false
+	}
+	*ve = (*ve)[:0]
+	for _, e := range es {
+		var fe FieldError
+		if errors.As(e.Err, &fe) {
+			*ve = append(*ve, fe)
+		}
+	}
+	return len(*ve) > 0
+}
+
+// validationErrors converts the ValidationErrors of an engine into Errors.
+func validationErrors(err error) error {
+	var ve ValidationErrors
+	if !errors.As(err, &ve) {
+		return err
+	}
+	es := make(Errors, len(ve))
+	for i, fe := range ve {
+		e := &Error{Field: fe.Field, Value: fe.Value, Err: fe}
+		if fe.Value != nil {
+			e.Type = fmt.Sprintf("%T", fe.Value)
+		}
+		es[i] = e
+	}
+	return es
+}
+
+// bodyError wraps a failure to decode the body. The field of JSON type errors is kept
+// as a JSON pointer, with their type and value.
+func bodyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		err = fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxBytesErr.Limit)
+	}
+	e := &Error{Source: SourceBody, Err: err}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field != "" {
+			e.Field = "/" + strings.ReplaceAll(typeErr.Field, ".", "/")
+		}
+		e.Type = typeErr.Type.String()
+		e.Value = typeErr.Value
+	}
+	return e
+}
+
+// rawValue returns the values of a key as reported by Error.
+func rawValue(vs []string) any {
+	switch len(vs) {
+	case 0:
+		return nil
+	case 1:
+		return vs[0]
+	default:
+		return vs
+	}
+}
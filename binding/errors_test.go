@@ -0,0 +1,55 @@
+package binding
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBindingErrors(t *testing.T) {
+	var q struct {
+		Page int `query:"page"`
+	}
+	req := httptest.NewRequest("GET", "/?page=x", nil)
+	err := Query.Bind(req, &q)
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("query error = %T %v", err, err)
+	}
+	if e.Source != SourceQuery || e.Field != "page" || e.Type != "int" || e.Value != "x" {
+		t.Errorf("query error = %+v", e)
+	}
+
+	var body struct {
+		User struct {
+			Age int `json:"age"`
+		} `json:"user"`
+	}
+	req = httptest.NewRequest("POST", "/", strings.NewReader(`{"user":{"age":"old"}}`))
+	err = JSON.Bind(req, &body)
+	if !errors.As(err, &e) {
+		t.Fatalf("json error = %T %v", err, err)
+	}
+	if e.Source != SourceBody || e.Field != "/user/age" || e.Type != "int" || e.Value != "string" {
+		t.Errorf("json error = %+v", e)
+	}
+
+	var v struct {
+		Name string `validate:"required"`
+		Age  int    `validate:"min=18"`
+	}
+	v.Age = 3
+	err = Validate(&v)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("validate error = %T %v", err, err)
+	}
+	if errs[1].Field != "Age" || errs[1].Type != "int" || errs[1].Value != 3 {
+		t.Errorf("validate error = %+v", errs[1])
+	}
+	var ve ValidationErrors
+	if !errors.As(err, &ve) || len(ve) != 2 || ve[0].Rule != "required" {
+		t.Errorf("ValidationErrors = %v", ve)
+	}
+}
@@ -0,0 +1,63 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+const defaultMemory = 32 << 20
+
+type formBinding struct{}
+type formPostBinding struct{}
+type formMultipartBinding struct{}
+
+func (formBinding) Name() string {
+	return "form"
+}
+
+func (formBinding) Bind(req *http.Request, obj any) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+	if err := req.ParseMultipartForm(defaultMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+
+	return mapForm(obj, req.Form)
+}
+
+func (formPostBinding) Name() string {
+	return "form-urlencoded"
+}
+
+func (formPostBinding) Bind(req *http.Request, obj any) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+
+	return mapForm(obj, req.PostForm)
+}
+
+func (formMultipartBinding) Name() string {
+	return "multipart/form-data"
+}
+
+// Bind binds the text fields and the files of a multipart form, files go to fields of type
+// *multipart.FileHeader, multipart.FileHeader or slices and arrays of them.
+// The maxsize and mimetypes tags of a file field limit the files it accepts,
+// see ErrFileTooLarge and ErrFileTypeNotAllowed.
+func (formMultipartBinding) Bind(req *http.Request, obj any) error {
+	if err := req.ParseMultipartForm(defaultMemory); err != nil {
+		return err
+	}
+
+	ptrVal := reflect.ValueOf(obj)
+	if ptrVal.Kind() == reflect.Ptr {
+		ptrVal = ptrVal.Elem()
+	}
+	if ptrVal.Kind() == reflect.Map {
+		return mapForm(obj, req.MultipartForm.Value)
+	}
+	return mappingByPtr(obj, (*multipartRequest)(req), "form")
+}
@@ -1,8 +1,15 @@
 package binding
 
 import (
+	"encoding"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 var ( // ErrConvertMapStringSlice can not convert to map[string][]string
@@ -10,6 +17,13 @@ var ( // ErrConvertMapStringSlice can not convert to map[string][]string
 
 	// ErrConvertToMapString can not convert to map[string]string
 	ErrConvertToMapString = errors.New("can not convert to map of strings")
+
+	errUnknownType = errors.New("unknown type")
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
 )
 
 func mapForm(ptr any, form map[string][]string) error {
@@ -31,7 +45,7 @@ func mapFormByTag(ptr any, form map[string][]string, tag string) error {
 		return setMapForm(ptr, form)
 	}
 
-	return mappingByPtr(ptr, form)
+	return mappingByPtr(ptr, formSource(form), tag)
 }
 
 func setMapForm(ptr any, form map[string][]string) error {
@@ -45,6 +59,7 @@ func setMapForm(ptr any, form map[string][]string) error {
 		for k, v := range form {
 			ptrMap[k] = v
 		}
+		return nil
 	}
 
 	ptrMap, ok := ptr.(map[string]string)
@@ -58,6 +73,469 @@ func setMapForm(ptr any, form map[string][]string) error {
 	return nil
 }
 
-func mappingByPtr(ptr any, form map[string][]string) error {
+// setter tries to set value on a walking by fields of a struct
+type setter interface {
+	TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (isSet bool, err error)
+}
+
+type formSource map[string][]string
+
+var _ setter = formSource(nil)
+
+// TrySet tries to set a value by request's form source (like map[string][]string)
+func (form formSource) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (isSet bool, err error) {
+	return setByForm(value, field, form, key, opt)
+}
+
+// setOptions holds the options of a field tag, such as `form:"page,default=1"`.
+type setOptions struct {
+	isDefaultExists bool
+	defaultValue    string
+	// sep splits the values of slices and arrays, set by the parser tag.
+	sep string
+	// maxSize is the limit of uploaded files set by the maxsize tag, when maxSizeTag is not empty.
+	maxSize    int64
+	maxSizeTag string
+}
+
+// fieldInfo is the cached tag information of a struct field.
+type fieldInfo struct {
+	index int
+	field reflect.StructField
+	// key is the name the field is looked up by, empty when the field carries no tag for the source.
+	key string
+	opt setOptions
+}
+
+type fieldCacheKey struct {
+	t   reflect.Type
+	tag string
+}
+
+// cachedFieldInfo holds the fields of a struct, or the error of the first tag that could not be parsed.
+type cachedFieldInfo struct {
+	fields []fieldInfo
+	err    error
+}
+
+// fieldCache maps a fieldCacheKey to the cachedFieldInfo of the struct, so tags are parsed once per type.
+var fieldCache sync.Map
+
+// cachedFields returns the bindable fields of the struct type t for tag.
+// Unexported fields and fields tagged "-" are left out, as are unexported embedded fields
+// other than structs, like encoding/json does. Fields without the tag are
+// looked up by their name for the form tag only, other sources just descend into them.
+// An unknown parser tag or a malformed maxsize tag is reported as ErrInvalidTag.
+func cachedFields(t reflect.Type, tag string) ([]fieldInfo, error) {
+	cacheKey := fieldCacheKey{t: t, tag: tag}
+	if cached, ok := fieldCache.Load(cacheKey); ok {
+		info := cached.(cachedFieldInfo)
+		return info.fields, info.err
+	}
+
+	fields := make([]fieldInfo, 0, t.NumField())
+	var err error
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && (!sf.Anonymous || sf.Type.Kind() != reflect.Struct) { // unexported
+			continue
+		}
+		tagValue := sf.Tag.Get(tag)
+		if tagValue == "-" {
+			continue
+		}
+
+		info := fieldInfo{index: i, field: sf}
+		info.opt.sep, err = parserSep(sf)
+		if err == nil {
+			info.opt.maxSize, info.opt.maxSizeTag, err = maxSizeTag(sf)
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: field %s of %s: %v", ErrInvalidTag, sf.Name, t, err)
+			fields = nil
+			break
+		}
+		var opts string
+		info.key, opts, _ = strings.Cut(tagValue, ",")
+		if info.key == "" && tag == "form" {
+			info.key = sf.Name
+		}
+		for opts != "" {
+			var opt string
+			opt, opts, _ = strings.Cut(opts, ",")
+			if k, v, _ := strings.Cut(opt, "="); k == "default" {
+				info.opt.isDefaultExists = true
+				info.opt.defaultValue = v
+			}
+		}
+		fields = append(fields, info)
+	}
+
+	cached, _ := fieldCache.LoadOrStore(cacheKey, cachedFieldInfo{fields: fields, err: err})
+	info := cached.(cachedFieldInfo)
+	return info.fields, info.err
+}
+
+func mappingByPtr(ptr any, setter setter, tag string) error {
+	_, err := mapping(reflect.ValueOf(ptr), nil, setter, tag, map[reflect.Type]bool{})
+	return err
+}
+
+// mapping sets value from setter. info describes the struct field value is stored in,
+// it is nil for the value passed to mappingByPtr. visited holds the struct types being
+// descended into, so that a type referring to itself, such as a Parent *T field, is not
+// allocated and walked forever.
+func mapping(value reflect.Value, info *fieldInfo, setter setter, tag string, visited map[reflect.Type]bool) (bool, error) {
+	vKind := value.Kind()
+
+	if vKind == reflect.Ptr && value.Type() != fileHeaderPtrType {
+		var isNew bool
+		vPtr := value
+		if value.IsNil() {
+			if !value.CanSet() {
+				return false, nil
+			}
+			isNew = true
+			vPtr = reflect.New(value.Type().Elem())
+		}
+		isSet, err := mapping(vPtr.Elem(), info, setter, tag, visited)
+		if err != nil {
+			return false, err
+		}
+		if isNew && isSet {
+			value.Set(vPtr)
+		}
+		return isSet, nil
+	}
+
+	if info != nil && info.key != "" && (vKind != reflect.Struct || !info.field.Anonymous) {
+		ok, err := setter.TrySet(value, info.field, info.key, info.opt)
+		if err != nil {
+			return false, fieldError(err, tag, info.key, value)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	if vKind != reflect.Struct || value.Type() == timeType || value.Type() == fileHeaderPtrType.Elem() ||
+		info != nil && hasDecoder(value) {
+		return false, nil
+	}
+
+	t := value.Type()
+	if visited[t] {
+		return false, nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	var isSet bool
+	fields, err := cachedFields(t, tag)
+	if err != nil {
+		return false, err
+	}
+	for i := range fields {
+		ok, err := mapping(value.Field(fields[i].index), &fields[i], setter, tag, visited)
+		if err != nil {
+			return false, err
+		}
+		isSet = isSet || ok
+	}
+	return isSet, nil
+}
+
+// fieldError reports err as an Error of the field bound from key of source.
+func fieldError(err error, source, key string, value reflect.Value) error {
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{Field: key, Type: value.Type().String(), Err: err}
+	}
+	if e.Source == "" {
+		e.Source = source
+	}
+	return e
+}
+
+func setByForm(value reflect.Value, field reflect.StructField, form map[string][]string, key string, opt setOptions) (isSet bool, err error) {
+	vs, ok := form[key]
+	defer func() {
+		if err != nil {
+			err = &Error{Field: key, Type: value.Type().String(), Value: rawValue(vs), Err: err}
+		}
+	}()
+	if value.Kind() == reflect.Map {
+		return setFormMap(value, field, form, key)
+	}
+	if !ok && !opt.isDefaultExists {
+		return false, nil
+	}
+
+	switch value.Kind() {
+	case reflect.Slice:
+		if !ok {
+			vs = strings.Split(opt.defaultValue, ";")
+		}
+		if len(vs) > 0 && hasDecoder(value) {
+			return true, setWithProperType(vs[0], value, field)
+		}
+		if opt.sep != "" {
+			vs = splitValues(vs, opt.sep)
+		}
+		return true, setSlice(vs, value, field)
+	case reflect.Array:
+		if !ok {
+			vs = strings.Split(opt.defaultValue, ";")
+		}
+		if len(vs) > 0 && hasDecoder(value) {
+			return true, setWithProperType(vs[0], value, field)
+		}
+		if opt.sep != "" {
+			vs = splitValues(vs, opt.sep)
+		}
+		if len(vs) != value.Len() {
+			return false, fmt.Errorf("%q is not valid value for %s", vs, value.Type().String())
+		}
+		return true, setArray(vs, value, field)
+	default:
+		var val string
+		if !ok {
+			val = opt.defaultValue
+		}
+		if len(vs) > 0 {
+			val = vs[0]
+		}
+		return true, setWithProperType(val, value, field)
+	}
+}
+
+// setFormMap fills a map field from the keys written as key[name], such as ids[a]=1&ids[b]=2.
+func setFormMap(value reflect.Value, field reflect.StructField, form map[string][]string, key string) (bool, error) {
+	prefix := key + "["
+	var isSet bool
+	for k, vs := range form {
+		if len(vs) == 0 || !strings.HasPrefix(k, prefix) || !strings.HasSuffix(k, "]") {
+			continue
+		}
+		if value.IsNil() {
+			value.Set(reflect.MakeMap(value.Type()))
+		}
+
+		mapKey := reflect.New(value.Type().Key()).Elem()
+		if err := setWithProperType(k[len(prefix):len(k)-1], mapKey, field); err != nil {
+			return false, err
+		}
+		mapElem := reflect.New(value.Type().Elem()).Elem()
+		var err error
+		if mapElem.Kind() == reflect.Slice {
+			err = setSlice(vs, mapElem, field)
+		} else {
+			err = setWithProperType(vs[0], mapElem, field)
+		}
+		if err != nil {
+			return false, err
+		}
+		value.SetMapIndex(mapKey, mapElem)
+		isSet = true
+	}
+	return isSet, nil
+}
+
+func isTextUnmarshaler(value reflect.Value) bool {
+	return value.CanAddr() && value.Addr().Type().Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem())
+}
+
+// setWithProperType sets value from val. Registered decoders and BindUnmarshaler come first,
+// then time.Time, time.Duration and encoding.TextUnmarshaler, then the kind of value.
+func setWithProperType(val string, value reflect.Value, field reflect.StructField) error {
+	if ok, err := decodeCustom(val, value); ok {
+		return err
+	}
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return setWithProperType(val, value.Elem(), field)
+	}
+
+	switch value.Type() {
+	case timeType:
+		return setTimeField(val, field, value)
+	case durationType:
+		return setTimeDuration(val, value)
+	}
+	if isTextUnmarshaler(value) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+
+	switch value.Kind() {
+	case reflect.Int:
+		return setIntField(val, 0, value)
+	case reflect.Int8:
+		return setIntField(val, 8, value)
+	case reflect.Int16:
+		return setIntField(val, 16, value)
+	case reflect.Int32:
+		return setIntField(val, 32, value)
+	case reflect.Int64:
+		return setIntField(val, 64, value)
+	case reflect.Uint:
+		return setUintField(val, 0, value)
+	case reflect.Uint8:
+		return setUintField(val, 8, value)
+	case reflect.Uint16:
+		return setUintField(val, 16, value)
+	case reflect.Uint32:
+		return setUintField(val, 32, value)
+	case reflect.Uint64:
+		return setUintField(val, 64, value)
+	case reflect.Bool:
+		return setBoolField(val, value)
+	case reflect.Float32:
+		return setFloatField(val, 32, value)
+	case reflect.Float64:
+		return setFloatField(val, 64, value)
+	case reflect.String:
+		value.SetString(val)
+	case reflect.Interface:
+		if value.NumMethod() > 0 {
+			return errUnknownType
+		}
+		value.Set(reflect.ValueOf(val))
+	case reflect.Struct, reflect.Map:
+		return json.Unmarshal([]byte(val), value.Addr().Interface())
+	default:
+		return errUnknownType
+	}
+	return nil
+}
+
+func setIntField(val string, bitSize int, field reflect.Value) error {
+	if val == "" {
+		val = "0"
+	}
+	intVal, err := strconv.ParseInt(val, 10, bitSize)
+	if err == nil {
+		field.SetInt(intVal)
+	}
+	return err
+}
+
+func setUintField(val string, bitSize int, field reflect.Value) error {
+	if val == "" {
+		val = "0"
+	}
+	uintVal, err := strconv.ParseUint(val, 10, bitSize)
+	if err == nil {
+		field.SetUint(uintVal)
+	}
+	return err
+}
+
+func setBoolField(val string, field reflect.Value) error {
+	if val == "" {
+		val = "false"
+	}
+	boolVal, err := strconv.ParseBool(val)
+	if err == nil {
+		field.SetBool(boolVal)
+	}
+	return err
+}
+
+func setFloatField(val string, bitSize int, field reflect.Value) error {
+	if val == "" {
+		val = "0.0"
+	}
+	floatVal, err := strconv.ParseFloat(val, bitSize)
+	if err == nil {
+		field.SetFloat(floatVal)
+	}
+	return err
+}
+
+// setTimeField parses val with the layout of the time_format tag, RFC 3339 by default.
+// time_format also accepts unix, unixmilli, unixmicro and unixnano for epoch values,
+// time_utc:"1" and time_location select the location of layouts without a zone.
+func setTimeField(val string, structField reflect.StructField, value reflect.Value) error {
+	timeFormat := structField.Tag.Get("time_format")
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+
+	if val == "" {
+		value.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+
+	switch tf := strings.ToLower(timeFormat); tf {
+	case "unix", "unixmilli", "unixmicro", "unixnano":
+		tv, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		var t time.Time
+		switch tf {
+		case "unix":
+			t = time.Unix(tv, 0)
+		case "unixmilli":
+			t = time.UnixMilli(tv)
+		case "unixmicro":
+			t = time.UnixMicro(tv)
+		default:
+			t = time.Unix(0, tv)
+		}
+		value.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	l := time.Local
+	if isUTC, _ := strconv.ParseBool(structField.Tag.Get("time_utc")); isUTC {
+		l = time.UTC
+	}
+	if locTag := structField.Tag.Get("time_location"); locTag != "" {
+		loc, err := time.LoadLocation(locTag)
+		if err != nil {
+			return err
+		}
+		l = loc
+	}
+
+	t, err := time.ParseInLocation(timeFormat, val, l)
+	if err != nil {
+		return err
+	}
+	value.Set(reflect.ValueOf(t))
+	return nil
+}
+
+func setTimeDuration(val string, value reflect.Value) error {
+	if val == "" {
+		val = "0"
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return err
+	}
+	value.SetInt(int64(d))
+	return nil
+}
+
+func setArray(vals []string, value reflect.Value, field reflect.StructField) error {
+	for i, s := range vals {
+		if err := setWithProperType(s, value.Index(i), field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setSlice(vals []string, value reflect.Value, field reflect.StructField) error {
+	slice := reflect.MakeSlice(value.Type(), len(vals), len(vals))
+	if err := setArray(vals, slice, field); err != nil {
+		return err
+	}
+	value.Set(slice)
 	return nil
 }
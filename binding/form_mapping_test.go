@@ -0,0 +1,222 @@
+package binding
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Pagination struct {
+	Page int `form:"page,default=1"`
+	Size int `form:"size,default=20"`
+}
+
+type mappingFilter struct {
+	Tags []string `form:"tag"`
+}
+
+type mappingTarget struct {
+	Pagination
+	Filter   mappingFilter
+	Name     *string           `form:"name"`
+	IDs      [2]int            `form:"id"`
+	Labels   map[string]string `form:"labels"`
+	Since    time.Time         `form:"since" time_format:"2006-01-02" time_utc:"1"`
+	Unix     time.Time         `form:"unix" time_format:"unix"`
+	Timeout  time.Duration     `form:"timeout"`
+	Addr     net.IP            `form:"addr"`
+	Ratio    float32           `form:"ratio"`
+	Active   bool              `form:"active"`
+	Sorts    []string          `form:"sort,default=id;name"`
+	Ignored  string            `form:"-"`
+	Untagged string
+	hidden   string
+}
+
+func TestMapForm(t *testing.T) {
+	form := map[string][]string{
+		"size":        {"50"},
+		"tag":         {"go", "web"},
+		"name":        {"gem"},
+		"id":          {"3", "4"},
+		"labels[env]": {"prod"},
+		"since":       {"2024-05-01"},
+		"unix":        {"1700000000"},
+		"timeout":     {"1m30s"},
+		"addr":        {"10.0.0.1"},
+		"ratio":       {"0.5"},
+		"active":      {"true"},
+		"Ignored":     {"x"},
+		"Untagged":    {"by name"},
+		"hidden":      {"x"},
+	}
+
+	var got mappingTarget
+	if err := mapForm(&got, form); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "gem"
+	want := mappingTarget{
+		Pagination: Pagination{Page: 1, Size: 50},
+		Filter:     mappingFilter{Tags: []string{"go", "web"}},
+		Name:       &name,
+		IDs:        [2]int{3, 4},
+		Labels:     map[string]string{"env": "prod"},
+		Since:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Unix:       time.Unix(1700000000, 0),
+		Timeout:    90 * time.Second,
+		Addr:       net.ParseIP("10.0.0.1"),
+		Ratio:      0.5,
+		Active:     true,
+		Sorts:      []string{"id", "name"},
+		Untagged:   "by name",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestMapFormErrors(t *testing.T) {
+	tests := []struct {
+		obj  any
+		form map[string][]string
+	}{
+		{&struct {
+			N int `form:"n"`
+		}{}, map[string][]string{"n": {"x"}}},
+		{&struct {
+			A [2]int `form:"a"`
+		}{}, map[string][]string{"a": {"1"}}},
+		{&struct {
+			D time.Duration `form:"d"`
+		}{}, map[string][]string{"d": {"soon"}}},
+		{&struct {
+			T time.Time `form:"t"`
+		}{}, map[string][]string{"t": {"yesterday"}}},
+		{&struct {
+			C chan int `form:"c"`
+		}{}, map[string][]string{"c": {"1"}}},
+	}
+	for _, tt := range tests {
+		if err := mapForm(tt.obj, tt.form); err == nil {
+			t.Errorf("%T: expected error", tt.obj)
+		}
+	}
+}
+
+func TestMapFormMap(t *testing.T) {
+	got := make(map[string]string)
+	if err := mapForm(&got, map[string][]string{"a": {"1", "2"}}); err != nil || got["a"] != "2" {
+		t.Fatalf("map[string]string: %v %v", got, err)
+	}
+	gotSlice := make(map[string][]string)
+	if err := mapForm(gotSlice, map[string][]string{"a": {"1", "2"}}); err != nil || len(gotSlice["a"]) != 2 {
+		t.Fatalf("map[string][]string: %v %v", gotSlice, err)
+	}
+}
+
+func TestQueryHeaderUriBinding(t *testing.T) {
+	var obj struct {
+		ID      int    `uri:"id"`
+		Page    int    `query:"page,default=1"`
+		Query   string `query:"q"`
+		TraceID string `header:"x-trace-id"`
+		Lang    string `header:"Accept-Language,default=en"`
+		Body    string `json:"body"`
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/items/7?q=gem&body=no", nil)
+	req.Header.Set("X-Trace-Id", "abc")
+
+	if err := Uri.BindingUri(map[string][]string{"id": {"7"}, "Body": {"no"}}, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if err := Query.Bind(req, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if err := Header.Bind(req, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj.ID != 7 || obj.Page != 1 || obj.Query != "gem" || obj.TraceID != "abc" || obj.Lang != "en" || obj.Body != "" {
+		t.Fatalf("got %+v", obj)
+	}
+}
+
+type mappingNode struct {
+	Name     string `query:"name"`
+	Parent   *mappingNode
+	Children []*mappingNode `query:"child"`
+}
+
+func TestMapSelfReferencingStruct(t *testing.T) {
+	var node mappingNode
+	req := httptest.NewRequest(http.MethodGet, "/?name=root", nil)
+	if err := Query.Bind(req, &node); err != nil {
+		t.Fatal(err)
+	}
+	if node.Name != "root" || node.Parent != nil {
+		t.Fatalf("got %+v", node)
+	}
+
+	// A form field of a type that refers to itself.
+	var form struct {
+		Node mappingNode
+	}
+	if err := mapForm(&form, map[string][]string{"Name": {"x"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type mappingBase struct {
+	Size int `query:"size"`
+}
+
+func TestMapUnexportedEmbedded(t *testing.T) {
+	var ptr struct {
+		*mappingBase
+		Page int `query:"page"`
+	}
+	req := httptest.NewRequest(http.MethodGet, "/?page=2&size=10", nil)
+	if err := Query.Bind(req, &ptr); err != nil {
+		t.Fatal(err)
+	}
+	if ptr.Page != 2 || ptr.mappingBase != nil {
+		t.Fatalf("got %+v", ptr)
+	}
+
+	// Exported fields of an embedded struct value are still bound.
+	var val struct {
+		mappingBase
+		Page int `query:"page"`
+	}
+	if err := Query.Bind(req, &val); err != nil {
+		t.Fatal(err)
+	}
+	if val.Page != 2 || val.Size != 10 {
+		t.Fatalf("got %+v", val)
+	}
+}
+
+func TestPlainBinding(t *testing.T) {
+	var s string
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello"))
+	if err := PLAIN.Bind(req, &s); err != nil || s != "hello" {
+		t.Fatalf("got %q %v", s, err)
+	}
+	var ip net.IP
+	if err := PLAIN.BindBody([]byte("::1"), &ip); err != nil || !ip.Equal(net.IPv6loopback) {
+		t.Fatalf("got %v %v", ip, err)
+	}
+	var n int
+	if err := PLAIN.BindBody([]byte("1"), &n); err == nil {
+		t.Fatal("expected error for *int")
+	}
+	if Default(http.MethodPost, "text/plain; charset=utf-8") != PLAIN {
+		t.Fatal("text/plain is not registered")
+	}
+}
@@ -2,15 +2,25 @@ package binding
 
 import (
 	"net/http"
+	"net/textproto"
+	"reflect"
 )
 
-type headerBinding struct {
-}
+type headerBinding struct{}
 
-func (h headerBinding) Name() string {
+func (headerBinding) Name() string {
 	return "header"
 }
 
-func (h headerBinding) Bind(request *http.Request, a any) error {
-	panic("")
+func (headerBinding) Bind(req *http.Request, obj any) error {
+	return mappingByPtr(obj, headerSource(req.Header), "header")
+}
+
+type headerSource map[string][]string
+
+var _ setter = headerSource(nil)
+
+// TrySet looks key up in its canonical form, so `header:"x-request-id"` matches X-Request-Id.
+func (hs headerSource) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (bool, error) {
+	return setByForm(value, field, hs, textproto.CanonicalMIMEHeaderKey(key), opt)
 }
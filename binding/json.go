@@ -2,33 +2,234 @@ package binding
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/crazyfrankie/gem/codec"
+	"github.com/crazyfrankie/gem/internal/structfields"
 )
 
+var (
+	// ErrUnknownField is reported for object keys matching no field when
+	// JSONOptions.DisallowUnknownFields is set.
+	ErrUnknownField = errors.New("unknown field")
+	// ErrTrailingData is reported for data after the JSON value when
+	// JSONOptions.DisallowTrailingData is set.
+	ErrTrailingData = errors.New("trailing data after JSON value")
+	// ErrMaxDepth is reported for values nested deeper than JSONOptions.MaxDepth.
+	ErrMaxDepth = errors.New("maximum nesting depth exceeded")
+)
+
+// JSONOptions tune the JSON binding, the zero value decodes like encoding/json.
+// Violations are reported as an *Error whose Field is the JSON pointer of the value, such as "/items/0/name".
+type JSONOptions struct {
+	// DisallowUnknownFields rejects object keys matching no field of the target struct.
+	DisallowUnknownFields bool
+	// UseNumber decodes numbers into interface values as json.Number instead of float64.
+	UseNumber bool
+	// DisallowTrailingData rejects anything but whitespace after the JSON value.
+	DisallowTrailingData bool
+	// MaxDepth rejects values nesting more than MaxDepth objects and arrays, 0 means no limit.
+	MaxDepth int
+	// CaseSensitive matches object keys to field names exactly, keys that only differ
+	// in case are treated as unknown fields instead of being bound.
+	CaseSensitive bool
+}
+
+// JSONWith returns a JSON binding decoding with opts, use it with Context.ShouldBindWith
+// to change the options of a single call.
+func JSONWith(opts JSONOptions) BindingBody {
+	return jsonBinding{opts: opts}
+}
+
 type jsonBinding struct {
+	opts  JSONOptions
+	codec codec.Codec
 }
 
 func (j jsonBinding) Name() string {
 	return "json"
 }
 
+// Bind decodes the body with the codec of the binding, codec.JSON by default,
+// or with protojson when obj is a proto.Message.
 func (j jsonBinding) Bind(request *http.Request, obj any) error {
-	decoder := json.NewDecoder(request.Body)
+	if _, ok := obj.(proto.Message); ok || j.opts != (JSONOptions{}) {
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			return bodyError(err)
+		}
+		return j.BindBody(body, obj)
+	}
+
+	decoder := codecOr(j.codec, codec.JSON).NewDecoder(request.Body)
 
 	if err := decoder.Decode(obj); err != nil {
-		return err
+		return bodyError(err)
 	}
 
 	return nil
 }
 
 func (j jsonBinding) BindBody(body []byte, obj any) error {
-	decoder := json.NewDecoder(bytes.NewReader(body))
+	if msg, ok := obj.(proto.Message); ok {
+		return bodyError(protojson.Unmarshal(body, msg))
+	}
+
+	if j.opts.DisallowTrailingData && hasTrailingData(body) {
+		return &Error{Source: SourceBody, Err: ErrTrailingData}
+	}
+
+	// Unknown fields, case and depth are checked on a generic decoding of the body first,
+	// which keys differing in case are removed from before the body is decoded into obj.
+	if j.opts.DisallowUnknownFields || j.opts.CaseSensitive || j.opts.MaxDepth > 0 {
+		var tree any
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&tree); err != nil {
+			return bodyError(err)
+		}
+		pruned, err := j.check(tree, reflect.TypeOf(obj), "", 1)
+		if err != nil {
+			return err
+		}
+		if pruned {
+			if body, err = json.Marshal(tree); err != nil {
+				return bodyError(err)
+			}
+		}
+	}
+
+	decoder := codecOr(j.codec, codec.JSON).NewDecoder(bytes.NewReader(body))
+	if d, ok := decoder.(interface{ UseNumber() }); ok && j.opts.UseNumber {
+		d.UseNumber()
+	}
+	if d, ok := decoder.(interface{ DisallowUnknownFields() }); ok && j.opts.DisallowUnknownFields {
+		d.DisallowUnknownFields()
+	}
 
 	if err := decoder.Decode(obj); err != nil {
-		return err
+		return bodyError(err)
 	}
 
 	return nil
 }
+
+// hasTrailingData reports whether anything but whitespace follows the first JSON value of body.
+// Bodies that are not valid JSON are left to the decoder to report.
+func hasTrailingData(body []byte) bool {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	var value json.RawMessage
+	if decoder.Decode(&value) != nil {
+		return false
+	}
+	_, err := decoder.Token()
+	return err != io.EOF
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// check walks the generic decoding v of the value at ptr, bound to t, and reports whether
+// keys were removed from it. t is nil when the value is not bound to a known type.
+func (j jsonBinding) check(v any, t reflect.Type, ptr string, depth int) (pruned bool, err error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t != nil && (reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType)) {
+		t = nil
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		if j.opts.MaxDepth > 0 && depth > j.opts.MaxDepth {
+			return false, &Error{Source: SourceBody, Field: ptr, Err: fmt.Errorf("%w: %d", ErrMaxDepth, j.opts.MaxDepth)}
+		}
+		var fields []structfields.Field
+		if t != nil && t.Kind() == reflect.Struct {
+			fields = structfields.Of(t, "json")
+		}
+		// Keys are walked in order so the same body always reports the same error.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			var elem reflect.Type
+			switch {
+			case fields != nil:
+				f, ok := j.field(fields, key)
+				switch {
+				case ok:
+					elem = f.Type
+				case j.opts.DisallowUnknownFields:
+					return false, &Error{Source: SourceBody, Field: ptr + "/" + escapePointer(key), Err: ErrUnknownField}
+				case j.opts.CaseSensitive && hasFoldedField(fields, key):
+					// encoding/json would bind the key to the field differing in case.
+					delete(v, key)
+					pruned = true
+					continue
+				}
+			case t != nil && t.Kind() == reflect.Map:
+				elem = t.Elem()
+			}
+			p, err := j.check(v[key], elem, ptr+"/"+escapePointer(key), depth+1)
+			if err != nil {
+				return false, err
+			}
+			pruned = pruned || p
+		}
+	case []any:
+		if j.opts.MaxDepth > 0 && depth > j.opts.MaxDepth {
+			return false, &Error{Source: SourceBody, Field: ptr, Err: fmt.Errorf("%w: %d", ErrMaxDepth, j.opts.MaxDepth)}
+		}
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for i, e := range v {
+			p, err := j.check(e, elem, fmt.Sprintf("%s/%d", ptr, i), depth+1)
+			if err != nil {
+				return false, err
+			}
+			pruned = pruned || p
+		}
+	}
+	return pruned, nil
+}
+
+// field returns the field key is decoded into, matched exactly when CaseSensitive is set.
+func (j jsonBinding) field(fields []structfields.Field, key string) (structfields.Field, bool) {
+	if !j.opts.CaseSensitive {
+		return structfields.ByName(fields, key)
+	}
+	for _, f := range fields {
+		if f.Name == key {
+			return f, true
+		}
+	}
+	return structfields.Field{}, false
+}
+
+func hasFoldedField(fields []structfields.Field, key string) bool {
+	_, ok := structfields.ByName(fields, key)
+	return ok
+}
+
+// escapePointer escapes a key as a JSON pointer reference token, see RFC 6901.
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
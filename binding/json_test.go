@@ -0,0 +1,58 @@
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type jsonItem struct {
+	Name string `json:"name"`
+}
+
+type jsonOrder struct {
+	ID    int            `json:"id"`
+	Items []jsonItem     `json:"items"`
+	Meta  map[string]any `json:"meta"`
+}
+
+func TestJSONOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  JSONOptions
+		body  string
+		field string
+		err   error
+	}{
+		{"unknown nested", JSONOptions{DisallowUnknownFields: true}, `{"items":[{"name":"a"},{"nmae":"b"}]}`, "/items/1/nmae", ErrUnknownField},
+		{"unknown escaped", JSONOptions{DisallowUnknownFields: true}, `{"a/b~c":1}`, "/a~1b~0c", ErrUnknownField},
+		{"folded unknown", JSONOptions{DisallowUnknownFields: true, CaseSensitive: true}, `{"ID":1}`, "/ID", ErrUnknownField},
+		{"trailing", JSONOptions{DisallowTrailingData: true}, `{"id":1} {"id":2}`, "", ErrTrailingData},
+		{"depth", JSONOptions{MaxDepth: 3}, `{"meta":{"a":{"b":[]}}}`, "/meta/a/b", ErrMaxDepth},
+		{"type", JSONOptions{}, `{"items":[{"name":1}]}`, "/items/0/name", nil},
+	}
+	for _, tt := range tests {
+		var obj jsonOrder
+		err := JSONWith(tt.opts).BindBody([]byte(tt.body), &obj)
+		var e *Error
+		if !errors.As(err, &e) || e.Source != SourceBody || e.Field != tt.field || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+
+	var obj jsonOrder
+	body := `{"ID":7,"id":1,"Items":[{"Name":"x"}],"meta":{"n":12345678901234567890}} `
+	if err := JSONWith(JSONOptions{CaseSensitive: true, UseNumber: true, DisallowTrailingData: true, MaxDepth: 3}).BindBody([]byte(body), &obj); err != nil {
+		t.Fatal(err)
+	}
+	want := jsonOrder{ID: 1, Meta: map[string]any{"n": json.Number("12345678901234567890")}}
+	if !reflect.DeepEqual(obj, want) {
+		t.Errorf("case sensitive = %+v, want %+v", obj, want)
+	}
+
+	obj = jsonOrder{}
+	if err := JSON.BindBody([]byte(`{"ID":7} trailing`), &obj); err != nil || obj.ID != 7 {
+		t.Errorf("default = %+v, %v", obj, err)
+	}
+}
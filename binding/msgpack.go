@@ -0,0 +1,27 @@
+package binding
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/crazyfrankie/gem/internal/msgpack"
+)
+
+type msgpackBinding struct{}
+
+func (msgpackBinding) Name() string {
+	return "msgpack"
+}
+
+func (b msgpackBinding) Bind(req *http.Request, obj any) error {
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		return bodyError(err)
+	}
+
+	return b.BindBody(buf, obj)
+}
+
+func (msgpackBinding) BindBody(body []byte, obj any) error {
+	return bodyError(msgpack.Unmarshal(body, obj))
+}
@@ -0,0 +1,169 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrFileTooLarge is returned when an uploaded file exceeds the maxsize tag of its field.
+	ErrFileTooLarge = errors.New("file too large")
+
+	// ErrFileTypeNotAllowed is returned when the sniffed type of an uploaded file
+	// is not listed in the mimetypes tag of its field.
+	ErrFileTypeNotAllowed = errors.New("file type not allowed")
+)
+
+var fileHeaderPtrType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// sniffLen is the number of bytes http.DetectContentType considers.
+const sniffLen = 512
+
+type multipartRequest http.Request
+
+var _ setter = (*multipartRequest)(nil)
+
+// TrySet tries to set a value by the multipart request with the binding a form file
+func (r *multipartRequest) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (bool, error) {
+	if files := r.MultipartForm.File[key]; len(files) != 0 {
+		return setByMultipartFormFile(value, field, files, opt)
+	}
+
+	return setByForm(value, field, r.MultipartForm.Value, key, opt)
+}
+
+// setByMultipartFormFile sets *multipart.FileHeader, multipart.FileHeader and slices or arrays
+// of them, after checking every file against the maxsize and mimetypes tags of field.
+func setByMultipartFormFile(value reflect.Value, field reflect.StructField, files []*multipart.FileHeader, opt setOptions) (isSet bool, err error) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.Type() == fileHeaderPtrType {
+			if err := checkFile(field, opt, files[0]); err != nil {
+				return false, err
+			}
+			value.Set(reflect.ValueOf(files[0]))
+			return true, nil
+		}
+	case reflect.Struct:
+		if value.Type() == fileHeaderPtrType.Elem() {
+			if err := checkFile(field, opt, files[0]); err != nil {
+				return false, err
+			}
+			value.Set(reflect.ValueOf(*files[0]))
+			return true, nil
+		}
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), len(files), len(files))
+		isSet, err = setArrayOfMultipartFormFiles(slice, field, files, opt)
+		if err != nil || !isSet {
+			return isSet, err
+		}
+		value.Set(slice)
+		return true, nil
+	case reflect.Array:
+		return setArrayOfMultipartFormFiles(value, field, files, opt)
+	}
+	return false, nil
+}
+
+func setArrayOfMultipartFormFiles(value reflect.Value, field reflect.StructField, files []*multipart.FileHeader, opt setOptions) (isSet bool, err error) {
+	if value.Len() != len(files) {
+		return false, nil
+	}
+	for i := range files {
+		set, err := setByMultipartFormFile(value.Index(i), field, files[i:i+1], opt)
+		if err != nil || !set {
+			return set, err
+		}
+	}
+	return true, nil
+}
+
+// checkFile enforces the limits of field on file. The maxsize tag, parsed with the field
+// into opt, takes a size such as 512KB or 2MB, where KB, MB and GB are powers of 1024.
+// The mimetypes tag lists the allowed types separated by commas, such as "image/png,image/*";
+// they are compared with the type sniffed from the content, the Content-Type sent by the
+// client is not trusted.
+func checkFile(field reflect.StructField, opt setOptions, file *multipart.FileHeader) error {
+	if opt.maxSizeTag != "" && file.Size > opt.maxSize {
+		return fmt.Errorf("binding: file %q of field %s is larger than %s: %w", file.Filename, field.Name, opt.maxSizeTag, ErrFileTooLarge)
+	}
+
+	if mimeTypes := field.Tag.Get("mimetypes"); mimeTypes != "" {
+		detected, err := sniffFile(file)
+		if err != nil {
+			return err
+		}
+		for _, allowed := range strings.Split(mimeTypes, ",") {
+			if matchMediaType(strings.TrimSpace(allowed), detected) {
+				return nil
+			}
+		}
+		return fmt.Errorf("binding: file %q of field %s is %s: %w", file.Filename, field.Name, detected, ErrFileTypeNotAllowed)
+	}
+	return nil
+}
+
+// sniffFile returns the media type of the content of file.
+func sniffFile(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return mediaType(http.DetectContentType(buf[:n])), nil
+}
+
+// matchMediaType reports whether the media type mime matches pattern, which may end with /*.
+func matchMediaType(pattern, mime string) bool {
+	pattern = mediaType(pattern)
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mime, prefix+"/")
+	}
+	return pattern == "*/*" || pattern == mime
+}
+
+// maxSizeTag returns the limit set by the maxsize tag of field and the tag itself.
+func maxSizeTag(field reflect.StructField) (int64, string, error) {
+	tag := field.Tag.Get("maxsize")
+	if tag == "" {
+		return 0, "", nil
+	}
+	limit, err := parseSize(tag)
+	if err != nil {
+		return 0, "", fmt.Errorf("bad maxsize: %v", err)
+	}
+	return limit, tag, nil
+}
+
+// parseSize parses sizes like 1024, 512KB, 10MB or 1GB, units are powers of 1024.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if trimmed, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, multiplier = strings.TrimSpace(trimmed), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
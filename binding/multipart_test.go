@@ -0,0 +1,105 @@
+package binding
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type uploadForm struct {
+	Title  string                  `form:"title"`
+	Avatar *multipart.FileHeader   `form:"avatar" maxsize:"1KB" mimetypes:"image/png,image/jpeg"`
+	Docs   []*multipart.FileHeader `form:"docs" mimetypes:"text/*"`
+	Raw    multipart.FileHeader    `form:"raw"`
+}
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string][][]byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for k, contents := range files {
+		for _, content := range contents {
+			// The part Content-Type claims an image, checks must sniff the content instead.
+			w, err := mw.CreateFormFile(k, k+".png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(content)
+		}
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestFormMultipartFiles(t *testing.T) {
+	req := newMultipartRequest(t, map[string]string{"title": "hello"}, map[string][][]byte{
+		"avatar": {pngHeader},
+		"docs":   {[]byte("first"), []byte("second")},
+		"raw":    {[]byte("raw")},
+	})
+
+	var form uploadForm
+	if err := FormMultipart.Bind(req, &form); err != nil {
+		t.Fatal(err)
+	}
+	if form.Title != "hello" || form.Avatar == nil || form.Avatar.Filename != "avatar.png" ||
+		len(form.Docs) != 2 || form.Raw.Size != 3 {
+		t.Fatalf("got %+v", form)
+	}
+	if form.Avatar != req.MultipartForm.File["avatar"][0] {
+		t.Fatal("Avatar must point to the parsed file header")
+	}
+}
+
+func TestFormMultipartFileLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string][][]byte
+		want  error
+	}{
+		{"too large", map[string][][]byte{"avatar": {append(pngHeader, make([]byte, 1024)...)}}, ErrFileTooLarge},
+		{"sniffed type", map[string][][]byte{"avatar": {[]byte("GIF89a not a png")}}, ErrFileTypeNotAllowed},
+		{"one of many", map[string][][]byte{"docs": {[]byte("text"), pngHeader}}, ErrFileTypeNotAllowed},
+	}
+	for _, tt := range tests {
+		var form uploadForm
+		err := FormMultipart.Bind(newMultipartRequest(t, nil, tt.files), &form)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestFormMultipartBadMaxSize(t *testing.T) {
+	var form struct {
+		Avatar *multipart.FileHeader `form:"avatar" maxsize:"lots"`
+	}
+	req := newMultipartRequest(t, nil, map[string][][]byte{"avatar": {pngHeader}})
+	if err := FormMultipart.Bind(req, &form); !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("got %v, want ErrInvalidTag", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{"100": 100, "2KB": 2 << 10, "10 MB": 10 << 20, "1GiB": 1 << 30, "7b": 7} {
+		if got, err := parseSize(s); err != nil || got != want {
+			t.Errorf("parseSize(%q) = %d, %v", s, got, err)
+		}
+	}
+	if _, err := parseSize("lots"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -1,21 +1,38 @@
 package binding
 
-import "net/http"
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"net/http"
+)
 
-type plainBinding struct {
-}
+type plainBinding struct{}
 
-func (p plainBinding) Name() string {
-	//TODO implement me
-	panic("implement me")
+func (plainBinding) Name() string {
+	return "plain"
 }
 
-func (p plainBinding) Bind(request *http.Request, a any) error {
-	//TODO implement me
-	panic("implement me")
+func (p plainBinding) Bind(req *http.Request, obj any) error {
+	all, err := io.ReadAll(req.Body)
+	if err != nil {
+		return bodyError(err)
+	}
+
+	return p.BindBody(all, obj)
 }
 
-func (p plainBinding) BindBody(bytes []byte, a any) error {
-	//TODO implement me
-	panic("implement me")
+// BindBody stores body into a *string, a *[]byte or an encoding.TextUnmarshaler.
+func (plainBinding) BindBody(body []byte, obj any) error {
+	switch v := obj.(type) {
+	case *string:
+		*v = string(body)
+	case *[]byte:
+		*v = body
+	case encoding.TextUnmarshaler:
+		return bodyError(v.UnmarshalText(body))
+	default:
+		return fmt.Errorf("type (%T) unknown type", obj)
+	}
+	return nil
 }
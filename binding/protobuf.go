@@ -0,0 +1,33 @@
+package binding
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type protobufBinding struct{}
+
+func (protobufBinding) Name() string {
+	return "protobuf"
+}
+
+func (b protobufBinding) Bind(req *http.Request, obj any) error {
+	buf, err := io.ReadAll(req.Body)
+	if err != nil {
+		return bodyError(err)
+	}
+
+	return b.BindBody(buf, obj)
+}
+
+func (protobufBinding) BindBody(body []byte, obj any) error {
+	msg, ok := obj.(proto.Message)
+	if !ok {
+		return errors.New("obj is not ProtoMessage")
+	}
+
+	return bodyError(proto.Unmarshal(body, msg))
+}
@@ -2,15 +2,12 @@ package binding
 
 import "net/http"
 
-type queryBinding struct {
-}
+type queryBinding struct{}
 
-func (q queryBinding) Name() string {
-	//TODO implement me
-	panic("implement me")
+func (queryBinding) Name() string {
+	return "query"
 }
 
-func (q queryBinding) Bind(request *http.Request, a any) error {
-	//TODO implement me
-	panic("implement me")
+func (queryBinding) Bind(req *http.Request, obj any) error {
+	return mapFormByTag(obj, req.URL.Query(), "query")
 }
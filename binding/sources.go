@@ -0,0 +1,78 @@
+package binding
+
+import (
+	"reflect"
+)
+
+// Values are the values of a request part, looked up by the fields tagged with Tag,
+// one of SourceForm, SourceQuery, SourceHeader, SourceCookie or SourceURI.
+type Values struct {
+	Tag    string
+	Values map[string][]string
+}
+
+// MapSources binds obj from several request parts, given from the lowest precedence to the highest,
+// so a field tagged for several of them keeps the value of the last one that has its key.
+// The default= option of a field is applied once all sources are bound, and only to fields
+// none of them supplied that still hold their zero value, such as fields a body left out.
+func MapSources(obj any, sources ...Values) error {
+	set := make(map[fieldAddr]bool)
+	tags := make([]string, 0, len(sources))
+	for _, src := range sources {
+		var s setter = formSource(src.Values)
+		if src.Tag == SourceHeader {
+			s = headerSource(src.Values)
+		}
+		if err := mappingByPtr(obj, trackingSetter{setter: s, set: set}, src.Tag); err != nil {
+			return err
+		}
+		tags = append(tags, src.Tag)
+	}
+	for _, tag := range tags {
+		if err := mappingByPtr(obj, defaultSetter{set: set}, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldAddr identifies a bound field, the type tells a struct apart from its first field.
+type fieldAddr struct {
+	ptr uintptr
+	t   reflect.Type
+}
+
+func addrOf(value reflect.Value) fieldAddr {
+	return fieldAddr{ptr: value.Addr().Pointer(), t: value.Type()}
+}
+
+// trackingSetter sets values without their defaults and records the fields it set.
+type trackingSetter struct {
+	setter
+	set map[fieldAddr]bool
+}
+
+func (s trackingSetter) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (bool, error) {
+	opt.isDefaultExists = false
+	ok, err := s.setter.TrySet(value, field, key, opt)
+	if ok && err == nil {
+		s.set[addrOf(value)] = true
+	}
+	return ok, err
+}
+
+// defaultSetter applies the defaults of the fields no source set.
+type defaultSetter struct {
+	set map[fieldAddr]bool
+}
+
+func (s defaultSetter) TrySet(value reflect.Value, field reflect.StructField, key string, opt setOptions) (bool, error) {
+	if !opt.isDefaultExists || value.Kind() == reflect.Map || s.set[addrOf(value)] || !value.IsZero() {
+		return false, nil
+	}
+	ok, err := setByForm(value, field, nil, key, opt)
+	if ok && err == nil {
+		s.set[addrOf(value)] = true
+	}
+	return ok, err
+}
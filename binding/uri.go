@@ -1,14 +1,11 @@
 package binding
 
-type uriBinding struct {
-}
+type uriBinding struct{}
 
-func (u uriBinding) Name() string {
-	//TODO implement me
-	panic("implement me")
+func (uriBinding) Name() string {
+	return "uri"
 }
 
-func (u uriBinding) BindingUri(m map[string][]string, a any) error {
-	//TODO implement me
-	panic("implement me")
+func (uriBinding) BindingUri(m map[string][]string, obj any) error {
+	return mapFormByTag(obj, m, "uri")
 }
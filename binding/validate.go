@@ -0,0 +1,585 @@
+package binding
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// StructValidator is the minimal interface which needs to be implemented in
+// order for it to be used as the validator engine.
+type StructValidator interface {
+	// ValidateStruct validates a struct, a pointer to one, or a slice or array of them.
+	// Any other type is not validated and nil is returned.
+	ValidateStruct(obj any) error
+}
+
+// Validator is the validator run by Validate, and so by every Context.Bind* and ShouldBind*.
+// The default engine is driven by `validate` tags, such as `validate:"required,min=3"`.
+// Set it to nil to disable validation, or replace it with another engine.
+var Validator StructValidator = defaultValidator{}
+
+// Validate validates obj with Validator. The ValidationErrors of the engine are
+// returned as Errors, which errors.As still converts back to ValidationErrors.
+func Validate(obj any) error {
+	if Validator == nil {
+		return nil
+	}
+	return validationErrors(Validator.ValidateStruct(obj))
+}
+
+// FieldLevel is what a ValidationFunc checks.
+type FieldLevel struct {
+	// Value is the field value, pointers are already dereferenced.
+	Value reflect.Value
+	// Param is the text after '=' in the rule, empty if there is none.
+	Param string
+	// Field is the struct field being validated, it is the parent field for dived elements.
+	Field reflect.StructField
+	// Parent is the struct holding Field, it is used by rules that compare fields.
+	Parent reflect.Value
+}
+
+// ValidationFunc reports whether fl satisfies a rule.
+type ValidationFunc func(fl FieldLevel) bool
+
+// FieldError describes a field that failed a rule.
+type FieldError struct {
+	// Field is the path of the field, such as "Items[0].Name" or "Labels[env]".
+	Field string
+	Rule  string
+	Param string
+	Value any
+}
+
+func (e FieldError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("field %s failed on the '%s' rule", e.Field, e.Rule)
+	}
+	return fmt.Sprintf("field %s failed on the '%s=%s' rule", e.Field, e.Rule, e.Param)
+}
+
+// ValidationErrors lists every violation found in a struct.
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, len(ve))
+	for i, e := range ve {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ErrInvalidTag is returned, wrapped with the field it is found on, for a tag the binding
+// can not apply, such as an unknown validation rule, a rule param that does not suit the field,
+// an invalid regexp or an unknown parser. It is a mistake in the code rather than in the request.
+var ErrInvalidTag = errors.New("binding: invalid tag")
+
+// paramCheck reports whether param suits a rule applied to values of type t, held by the struct parent.
+type paramCheck func(param string, t, parent reflect.Type) error
+
+var validations = struct {
+	sync.RWMutex
+	m map[string]ValidationFunc
+	// checks verify the params of the built-in rules when a tag is parsed.
+	checks map[string]paramCheck
+}{m: map[string]ValidationFunc{
+	"min":       sizeRule(func(c int) bool { return c >= 0 }),
+	"max":       sizeRule(func(c int) bool { return c <= 0 }),
+	"len":       sizeRule(func(c int) bool { return c == 0 }),
+	"eq":        valueRule(true),
+	"ne":        valueRule(false),
+	"gt":        sizeRule(func(c int) bool { return c > 0 }),
+	"gte":       sizeRule(func(c int) bool { return c >= 0 }),
+	"lt":        sizeRule(func(c int) bool { return c < 0 }),
+	"lte":       sizeRule(func(c int) bool { return c <= 0 }),
+	"eqfield":   fieldRule(func(c int) bool { return c == 0 }),
+	"nefield":   fieldRule(func(c int) bool { return c != 0 }),
+	"gtfield":   fieldRule(func(c int) bool { return c > 0 }),
+	"gtefield":  fieldRule(func(c int) bool { return c >= 0 }),
+	"ltfield":   fieldRule(func(c int) bool { return c < 0 }),
+	"ltefield":  fieldRule(func(c int) bool { return c <= 0 }),
+	"oneof":     isOneOf,
+	"regexp":    matchRegexp,
+	"email":     isEmail,
+	"url":       isURL,
+	"uuid":      isUUID,
+	"required":  hasValue,
+	"omitempty": func(FieldLevel) bool { return true },
+}, checks: map[string]paramCheck{
+	"min":      checkSize,
+	"max":      checkSize,
+	"len":      checkSize,
+	"eq":       checkValue,
+	"ne":       checkValue,
+	"gt":       checkSize,
+	"gte":      checkSize,
+	"lt":       checkSize,
+	"lte":      checkSize,
+	"eqfield":  checkField,
+	"nefield":  checkField,
+	"gtfield":  checkField,
+	"gtefield": checkField,
+	"ltfield":  checkField,
+	"ltefield": checkField,
+	"regexp":   checkRegexp,
+}}
+
+// RegisterValidation adds a rule to the default engine, or replaces an existing one.
+// Rules must be registered before the first validation of a type using them,
+// typically from an init function, as the tags of a type are only parsed once.
+func RegisterValidation(name string, fn ValidationFunc) {
+	if name == "" || name == "dive" || fn == nil {
+		panic("binding: RegisterValidation needs a name other than dive and a non-nil func")
+	}
+	validations.Lock()
+	defer validations.Unlock()
+	validations.m[name] = fn
+	delete(validations.checks, name)
+}
+
+// lookupValidation returns the rule name and its param check, if it has one.
+func lookupValidation(name string) (ValidationFunc, paramCheck, bool) {
+	validations.RLock()
+	defer validations.RUnlock()
+	fn, ok := validations.m[name]
+	return fn, validations.checks[name], ok
+}
+
+// rule is one rule of a `validate` tag.
+type rule struct {
+	name, param string
+	fn          ValidationFunc
+}
+
+// ruleSet holds the rules of a value, and with dive, the rules of its elements.
+type ruleSet struct {
+	rules []rule
+	dive  *ruleSet
+}
+
+// parseRules parses a `validate` tag for values of type t held by the struct parent.
+// Rules are separated by commas, a comma inside a param is written 0x2C.
+// The rules after dive apply to the elements of a slice, array or map.
+func parseRules(tag string, t, parent reflect.Type) (*ruleSet, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	rs := new(ruleSet)
+	for tag != "" {
+		var r string
+		r, tag, _ = strings.Cut(tag, ",")
+		if r = strings.TrimSpace(r); r == "" {
+			continue
+		}
+		if r == "dive" {
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+			default:
+				return nil, fmt.Errorf("dive on %s, which has no elements", t)
+			}
+			dive, err := parseRules(tag, t.Elem(), parent)
+			if err != nil {
+				return nil, err
+			}
+			rs.dive = dive
+			break
+		}
+		name, param, _ := strings.Cut(r, "=")
+		param = strings.ReplaceAll(param, "0x2C", ",")
+		fn, check, ok := lookupValidation(name)
+		if !ok {
+			return nil, fmt.Errorf("undefined validation rule %q", name)
+		}
+		if check != nil {
+			if err := check(param, t, parent); err != nil {
+				return nil, fmt.Errorf("rule %q: %w", r, err)
+			}
+		}
+		rs.rules = append(rs.rules, rule{name: name, param: param, fn: fn})
+	}
+	return rs, nil
+}
+
+// validateField is the cached `validate` tag of a struct field.
+type validateField struct {
+	index int
+	field reflect.StructField
+	rules *ruleSet
+}
+
+// validateFields are the parsed `validate` tags of a struct type, or the error
+// of the first tag that could not be parsed.
+type validateFields struct {
+	fields []validateField
+	err    error
+}
+
+// validateCache maps a struct type to its validateFields.
+var validateCache sync.Map
+
+func cachedValidateFields(t reflect.Type) ([]validateField, error) {
+	if cached, ok := validateCache.Load(t); ok {
+		vf := cached.(validateFields)
+		return vf.fields, vf.err
+	}
+
+	var vf validateFields
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("validate")
+		if (sf.PkgPath != "" && !sf.Anonymous) || tag == "-" {
+			continue
+		}
+		rules, err := parseRules(tag, sf.Type, t)
+		if err != nil {
+			vf = validateFields{err: fmt.Errorf("%w: field %s of %s: %v", ErrInvalidTag, sf.Name, t, err)}
+			break
+		}
+		vf.fields = append(vf.fields, validateField{index: i, field: sf, rules: rules})
+	}
+
+	cached, _ := validateCache.LoadOrStore(t, vf)
+	vf = cached.(validateFields)
+	return vf.fields, vf.err
+}
+
+type defaultValidator struct{}
+
+func (v defaultValidator) ValidateStruct(obj any) error {
+	if obj == nil {
+		return nil
+	}
+
+	var errs ValidationErrors
+	value := indirect(reflect.ValueOf(obj))
+	switch value.Kind() {
+	case reflect.Struct:
+		if err := v.validateStruct("", value, &errs); err != nil {
+			return err
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if elem := indirect(value.Index(i)); elem.Kind() == reflect.Struct {
+				if err := v.validateStruct("["+strconv.Itoa(i)+"]", elem, &errs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateStruct checks the fields of value, it only fails for tags that can not be parsed.
+func (v defaultValidator) validateStruct(path string, value reflect.Value, errs *ValidationErrors) error {
+	if value.Type() == timeType {
+		return nil
+	}
+	fields, err := cachedValidateFields(value.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		fieldPath := path
+		if !f.field.Anonymous {
+			fieldPath = joinPath(path, f.field.Name)
+		}
+		if err := v.validateValue(fieldPath, value.Field(f.index), value, f.field, f.rules, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateValue checks value against rs, stopping at the first failed rule,
+// then descends into its elements with dive, or into its fields if it is a struct.
+func (v defaultValidator) validateValue(path string, value, parent reflect.Value, field reflect.StructField, rs *ruleSet, errs *ValidationErrors) error {
+	for _, r := range rs.rules {
+		switch r.name {
+		case "omitempty":
+			if !hasValue(FieldLevel{Value: value}) {
+				return nil
+			}
+			continue
+		case "required":
+			if !hasValue(FieldLevel{Value: value}) {
+				*errs = append(*errs, FieldError{Field: path, Rule: r.name, Value: interfaceOf(value)})
+				return nil
+			}
+			continue
+		}
+
+		elem := indirect(value)
+		if !elem.IsValid() || elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+			// A nil pointer only fails required.
+			return nil
+		}
+		fl := FieldLevel{Value: elem, Param: r.param, Field: field, Parent: parent}
+		if !r.fn(fl) {
+			*errs = append(*errs, FieldError{Field: path, Rule: r.name, Param: r.param, Value: interfaceOf(elem)})
+			return nil
+		}
+	}
+
+	value = indirect(value)
+	if rs.dive != nil {
+		switch value.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < value.Len(); i++ {
+				if err := v.validateValue(path+"["+strconv.Itoa(i)+"]", value.Index(i), parent, field, rs.dive, errs); err != nil {
+					return err
+				}
+			}
+		case reflect.Map:
+			for _, key := range value.MapKeys() {
+				if err := v.validateValue(fmt.Sprintf("%s[%v]", path, key.Interface()), value.MapIndex(key), parent, field, rs.dive, errs); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if value.Kind() == reflect.Struct {
+		return v.validateStruct(path, value, errs)
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// indirect dereferences value until it is not a non-nil pointer or interface.
+func indirect(value reflect.Value) reflect.Value {
+	for (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && !value.IsNil() {
+		value = value.Elem()
+	}
+	return value
+}
+
+func interfaceOf(value reflect.Value) any {
+	if !value.IsValid() || !value.CanInterface() {
+		return nil
+	}
+	return value.Interface()
+}
+
+// hasValue reports whether the value is set: slices and maps must not be empty,
+// anything else must not be the zero value.
+func hasValue(fl FieldLevel) bool {
+	switch fl.Value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return fl.Value.Len() > 0
+	case reflect.Invalid:
+		return false
+	}
+	return !fl.Value.IsZero()
+}
+
+// sizeRule builds a rule comparing the value to its param: numbers by value, durations
+// by a duration param, and strings (in runes), slices, arrays and maps by length.
+// Params are checked by checkSize when the tag is parsed, a value of a kind the rule
+// does not support, which only an interface field can hold, fails the rule.
+func sizeRule(ok func(c int) bool) ValidationFunc {
+	return func(fl FieldLevel) bool {
+		c, valid := compareSize(fl.Value, fl.Param)
+		return valid && ok(c)
+	}
+}
+
+// compareSize compares v, or its length, to param.
+func compareSize(v reflect.Value, param string) (int, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		p, err := strconv.ParseInt(param, 10, 64)
+		return cmp.Compare(int64(utf8.RuneCountInString(v.String())), p), err == nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		p, err := strconv.ParseInt(param, 10, 64)
+		return cmp.Compare(int64(v.Len()), p), err == nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(param)
+			return cmp.Compare(v.Int(), int64(d)), err == nil
+		}
+		p, err := strconv.ParseInt(param, 10, 64)
+		return cmp.Compare(v.Int(), p), err == nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p, err := strconv.ParseUint(param, 10, 64)
+		return cmp.Compare(v.Uint(), p), err == nil
+	case reflect.Float32, reflect.Float64:
+		p, err := strconv.ParseFloat(param, 64)
+		return cmp.Compare(v.Float(), p), err == nil
+	}
+	return 0, false
+}
+
+// checkSize reports whether a size rule can compare values of type t to param.
+func checkSize(param string, t, _ reflect.Type) error {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	if t.Kind() == reflect.Struct || t.Kind() == reflect.Bool || t.Kind() == reflect.Complex64 ||
+		t.Kind() == reflect.Complex128 || t.Kind() == reflect.Chan || t.Kind() == reflect.Func {
+		return fmt.Errorf("size rules are not supported on %s", t)
+	}
+	if _, ok := compareSize(reflect.New(t).Elem(), param); !ok {
+		return fmt.Errorf("bad param %q for %s", param, t)
+	}
+	return nil
+}
+
+// valueRule builds a rule checking whether the value equals its param, or differs from it
+// when equal is not set. Strings are compared as they are, bools, numbers and durations once
+// the param is parsed as their type. Sizes are checked with len instead.
+func valueRule(equal bool) ValidationFunc {
+	return func(fl FieldLevel) bool {
+		eq, valid := equalsParam(fl.Value, fl.Param)
+		return valid && eq == equal
+	}
+}
+
+// equalsParam reports whether the scalar v equals param.
+func equalsParam(v reflect.Value, param string) (bool, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String() == param, true
+	case reflect.Bool:
+		b, err := strconv.ParseBool(param)
+		return v.Bool() == b, err == nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		c, ok := compareSize(v, param)
+		return c == 0, ok
+	}
+	return false, false
+}
+
+// checkValue reports whether eq and ne can compare values of type t to param.
+func checkValue(param string, t, _ reflect.Type) error {
+	switch t.Kind() {
+	case reflect.Interface:
+		return nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Errorf("eq and ne compare values, use len for the size of %s", t)
+	}
+	if _, ok := equalsParam(reflect.New(t).Elem(), param); !ok {
+		return fmt.Errorf("bad param %q for %s", param, t)
+	}
+	return nil
+}
+
+// checkField reports whether parent has the field a field rule compares to.
+func checkField(param string, _, parent reflect.Type) error {
+	if _, ok := parent.FieldByName(param); !ok {
+		return fmt.Errorf("%s has no field %q", parent, param)
+	}
+	return nil
+}
+
+// checkRegexp compiles the param of the regexp rule, caching it for matchRegexp.
+func checkRegexp(param string, _, _ reflect.Type) error {
+	re, err := regexp.Compile(param)
+	if err != nil {
+		return err
+	}
+	regexps.LoadOrStore(param, re)
+	return nil
+}
+
+// fieldRule builds a rule comparing the value to the sibling field named by its param.
+// Numbers, strings and times can be compared; a missing field or mismatched kinds fail the rule.
+func fieldRule(ok func(c int) bool) ValidationFunc {
+	return func(fl FieldLevel) bool {
+		if fl.Parent.Kind() != reflect.Struct {
+			return false
+		}
+		other := indirect(fl.Parent.FieldByName(fl.Param))
+		if !other.IsValid() {
+			return false
+		}
+		c, comparable := compareValues(fl.Value, other)
+		return comparable && ok(c)
+	}
+}
+
+func compareValues(a, b reflect.Value) (int, bool) {
+	if a.Type() == timeType && b.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time)), true
+	}
+	switch {
+	case a.CanInt() && b.CanInt():
+		return cmp.Compare(a.Int(), b.Int()), true
+	case a.CanUint() && b.CanUint():
+		return cmp.Compare(a.Uint(), b.Uint()), true
+	case a.CanFloat() && b.CanFloat():
+		return cmp.Compare(a.Float(), b.Float()), true
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return cmp.Compare(a.String(), b.String()), true
+	}
+	return 0, false
+}
+
+// isOneOf checks the value against the space separated values of the param.
+func isOneOf(fl FieldLevel) bool {
+	s := fmt.Sprint(interfaceOf(fl.Value))
+	for _, allowed := range strings.Fields(fl.Param) {
+		if s == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+var regexps sync.Map
+
+// matchRegexp matches the value against the param, compiled by checkRegexp.
+func matchRegexp(fl FieldLevel) bool {
+	re, ok := regexps.Load(fl.Param)
+	if !ok {
+		compiled, err := regexp.Compile(fl.Param)
+		if err != nil {
+			return false
+		}
+		re, _ = regexps.LoadOrStore(fl.Param, compiled)
+	}
+	return fl.Value.Kind() == reflect.String && re.(*regexp.Regexp).MatchString(fl.Value.String())
+}
+
+func isEmail(fl FieldLevel) bool {
+	if fl.Value.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(fl.Value.String())
+	return err == nil && addr.Name == "" && addr.Address == fl.Value.String()
+}
+
+func isURL(fl FieldLevel) bool {
+	if fl.Value.Kind() != reflect.String {
+		return false
+	}
+	u, err := url.Parse(fl.Value.String())
+	return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "")
+}
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func isUUID(fl FieldLevel) bool {
+	return fl.Value.Kind() == reflect.String && uuidRegexp.MatchString(fl.Value.String())
+}
@@ -0,0 +1,217 @@
+package binding
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type validateAddress struct {
+	City string `validate:"required"`
+	Zip  string `validate:"omitempty,len=5"`
+}
+
+type validateUser struct {
+	Name     string    `validate:"required,min=2,max=8"`
+	Email    string    `validate:"email"`
+	Site     string    `validate:"omitempty,url"`
+	ID       string    `validate:"uuid"`
+	Role     string    `validate:"oneof=admin user"`
+	Code     string    `validate:"regexp=^[A-Z]{2}[0-9]+$"`
+	Age      *int      `validate:"omitempty,gte=18"`
+	Start    time.Time `validate:"required"`
+	End      time.Time `validate:"gtfield=Start"`
+	Min      int       `validate:"ltfield=Max"`
+	Max      int
+	Timeout  time.Duration     `validate:"max=1m"`
+	Tags     []string          `validate:"required,dive,min=2"`
+	Labels   map[string]string `validate:"dive,oneof=a b"`
+	Address  validateAddress
+	Backups  []validateAddress `validate:"dive"`
+	Even     int               `validate:"even"`
+	internal string
+}
+
+func validUser() validateUser {
+	age := 30
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return validateUser{
+		Name:    "gem",
+		Email:   "gem@example.com",
+		ID:      "0b5e8d2c-1f5a-4a8e-9a52-1e2d3c4b5a69",
+		Role:    "admin",
+		Code:    "AB12",
+		Age:     &age,
+		Start:   start,
+		End:     start.Add(time.Hour),
+		Min:     1,
+		Max:     2,
+		Timeout: time.Second,
+		Tags:    []string{"go"},
+		Labels:  map[string]string{"x": "a"},
+		Address: validateAddress{City: "Berlin"},
+		Backups: []validateAddress{{City: "Paris", Zip: "75001"}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	RegisterValidation("even", func(fl FieldLevel) bool { return fl.Value.Int()%2 == 0 })
+
+	u := validUser()
+	if err := Validate(&u); err != nil {
+		t.Fatalf("valid user: %v", err)
+	}
+
+	age := 12
+	u.Name = "g"
+	u.Email = "Gem <gem@example.com>"
+	u.Site = "example.com"
+	u.ID = "nope"
+	u.Role = "root"
+	u.Code = "ab12"
+	u.Age = &age
+	u.End = u.Start
+	u.Min = 3
+	u.Timeout = time.Hour
+	u.Tags = []string{"go", "x"}
+	u.Labels = map[string]string{"k": "c"}
+	u.Address = validateAddress{Zip: "123"}
+	u.Backups = []validateAddress{{City: "Paris"}, {}}
+	u.Even = 3
+
+	var errs ValidationErrors
+	if !errors.As(Validate(&u), &errs) {
+		t.Fatal("expected ValidationErrors")
+	}
+	got := make(map[string]string)
+	for _, e := range errs {
+		got[e.Field] = e.Rule + "=" + e.Param
+	}
+	want := map[string]string{
+		"Name":            "min=2",
+		"Email":           "email=",
+		"Site":            "url=",
+		"ID":              "uuid=",
+		"Role":            "oneof=admin user",
+		"Code":            "regexp=^[A-Z]{2}[0-9]+$",
+		"Age":             "gte=18",
+		"End":             "gtfield=Start",
+		"Min":             "ltfield=Max",
+		"Timeout":         "max=1m",
+		"Tags[1]":         "min=2",
+		"Labels[k]":       "oneof=a b",
+		"Address.City":    "required=",
+		"Address.Zip":     "len=5",
+		"Backups[1].City": "required=",
+		"Even":            "even=",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %v\nwant %v", got, want)
+	}
+	if !strings.Contains(errs.Error(), "field Name failed on the 'min=2' rule") {
+		t.Fatalf("unexpected message %q", errs.Error())
+	}
+}
+
+func TestValidateRequiredAndNil(t *testing.T) {
+	var obj struct {
+		Tags []string `validate:"required"`
+		Ptr  *int     `validate:"min=1"`
+	}
+	var errs ValidationErrors
+	if !errors.As(Validate(&obj), &errs) || len(errs) != 1 || errs[0].Field != "Tags" {
+		t.Fatalf("got %v", errs)
+	}
+	if Validate(42) != nil || Validate(nil) != nil {
+		t.Fatal("non structs must not be validated")
+	}
+}
+
+func TestValidateEqNe(t *testing.T) {
+	type values struct {
+		Role    string        `validate:"eq=admin"`
+		Version string        `validate:"eq=2"`
+		Name    string        `validate:"ne=root"`
+		Count   int           `validate:"ne=0"`
+		Admin   bool          `validate:"eq=true"`
+		Timeout time.Duration `validate:"eq=1s"`
+		Ratio   float64       `validate:"ne=0.5"`
+	}
+	ok := values{Role: "admin", Version: "2", Name: "gem", Count: 1, Admin: true, Timeout: time.Second, Ratio: 1}
+	if err := Validate(&ok); err != nil {
+		t.Fatalf("valid values: %v", err)
+	}
+
+	// A string is compared by value, not by its length.
+	bad := values{Role: "user", Version: "ab", Name: "root", Timeout: time.Minute, Ratio: 0.5}
+	var errs ValidationErrors
+	if !errors.As(Validate(&bad), &errs) {
+		t.Fatalf("expected ValidationErrors")
+	}
+	got := make(map[string]string)
+	for _, e := range errs {
+		got[e.Field] = e.Rule + "=" + e.Param
+	}
+	want := map[string]string{
+		"Role":    "eq=admin",
+		"Version": "eq=2",
+		"Name":    "ne=root",
+		"Count":   "ne=0",
+		"Admin":   "eq=true",
+		"Timeout": "eq=1s",
+		"Ratio":   "ne=0.5",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %v\nwant %v", got, want)
+	}
+}
+
+func TestValidateInvalidTags(t *testing.T) {
+	tests := []struct {
+		obj  any
+		want string
+	}{
+		{&struct {
+			Name string `validate:"required,nope"`
+		}{}, `undefined validation rule "nope"`},
+		{&struct {
+			Name string `validate:"min=two"`
+		}{Name: "x"}, `bad param "two"`},
+		{&struct {
+			Timeout time.Duration `validate:"max=1 minute"`
+		}{}, `bad param "1 minute"`},
+		{&struct {
+			Code string `validate:"regexp=[a-"`
+		}{}, "missing closing ]"},
+		{&struct {
+			End int `validate:"gtfield=Start"`
+		}{}, `has no field "Start"`},
+		{&struct {
+			Name string `validate:"dive,min=1"`
+		}{}, "dive on string"},
+		{&struct {
+			Tags []string `validate:"eq=2"`
+		}{}, "use len"},
+		{&struct {
+			Admin bool `validate:"eq=yes"`
+		}{}, `bad param "yes"`},
+		{&struct {
+			Items []struct {
+				Size uint `validate:"lte=-1"`
+			} `validate:"dive"`
+		}{Items: make([]struct {
+			Size uint `validate:"lte=-1"`
+		}, 1)}, `bad param "-1"`},
+	}
+	for _, tt := range tests {
+		// Checked once per type, so twice to also go through the cache.
+		for range 2 {
+			err := Validate(tt.obj)
+			if !errors.Is(err, ErrInvalidTag) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%T: %v, want ErrInvalidTag with %q", tt.obj, err, tt.want)
+			}
+		}
+	}
+}
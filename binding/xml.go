@@ -2,11 +2,13 @@ package binding
 
 import (
 	"bytes"
-	"encoding/xml"
 	"net/http"
+
+	"github.com/crazyfrankie/gem/codec"
 )
 
 type xmlBinding struct {
+	codec codec.Codec
 }
 
 func (x xmlBinding) Name() string {
@@ -14,18 +16,18 @@ func (x xmlBinding) Name() string {
 }
 
 func (x xmlBinding) Bind(request *http.Request, obj any) error {
-	decoder := xml.NewDecoder(request.Body)
+	decoder := codecOr(x.codec, codec.XML).NewDecoder(request.Body)
 	if err := decoder.Decode(obj); err != nil {
-		return err
+		return bodyError(err)
 	}
 
 	return nil
 }
 
 func (x xmlBinding) BindBody(b []byte, obj any) error {
-	decoder := xml.NewDecoder(bytes.NewReader(b))
+	decoder := codecOr(x.codec, codec.XML).NewDecoder(bytes.NewReader(b))
 	if err := decoder.Decode(obj); err != nil {
-		return err
+		return bodyError(err)
 	}
 
 	return nil
@@ -2,11 +2,13 @@ package binding
 
 import (
 	"bytes"
-	"gopkg.in/yaml.v3"
 	"net/http"
+
+	"github.com/crazyfrankie/gem/codec"
 )
 
 type yamlBinding struct {
+	codec codec.Codec
 }
 
 func (y yamlBinding) Name() string {
@@ -14,18 +16,18 @@ func (y yamlBinding) Name() string {
 }
 
 func (y yamlBinding) Bind(request *http.Request, obj any) error {
-	decoder := yaml.NewDecoder(request.Body)
+	decoder := codecOr(y.codec, codec.YAML).NewDecoder(request.Body)
 	if err := decoder.Decode(obj); err != nil {
-		return err
+		return bodyError(err)
 	}
 
 	return nil
 }
 
 func (y yamlBinding) BindBody(b []byte, obj any) error {
-	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder := codecOr(y.codec, codec.YAML).NewDecoder(bytes.NewReader(b))
 	if err := decoder.Decode(obj); err != nil {
-		return err
+		return bodyError(err)
 	}
 
 	return nil
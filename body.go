@@ -0,0 +1,206 @@
+package gem
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/crazyfrankie/gem/binding"
+)
+
+// BufferedBody is the request body installed by BodyBuffer.
+// It can be read any number of times by seeking back to the start.
+type BufferedBody struct {
+	*bytes.Reader
+	data []byte
+}
+
+var _ io.ReadSeekCloser = (*BufferedBody)(nil)
+
+// Bytes returns the whole buffered body regardless of the read position.
+func (b *BufferedBody) Bytes() []byte {
+	return b.data
+}
+
+// Rewind moves the read position back to the start of the body.
+func (b *BufferedBody) Rewind() {
+	b.Reader.Reset(b.data)
+}
+
+// Close does nothing, so that a handler closing the body does not prevent later ones from reading it.
+func (b *BufferedBody) Close() error {
+	return nil
+}
+
+// BodyBuffer returns a middleware that reads request bodies of up to limit bytes into memory
+// and replaces Request.Body with a *BufferedBody, an io.ReadSeeker that later handlers can rewind.
+// The bytes are also kept for ShouldBindBodyWith and GetRawData.
+// Bodies larger than limit are rejected with 413.
+//
+// Use it in front of middleware that needs the raw body, such as signature verification or body logging.
+func BodyBuffer(limit int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		data, err := io.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) || errors.Is(err, binding.ErrBodyTooLarge) {
+				c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+				return
+			}
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		if int64(len(data)) > limit {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		_ = c.Request.Body.Close()
+
+		c.Request.Body = &BufferedBody{Reader: bytes.NewReader(data), data: data}
+		c.bodyBytes = data
+		c.Next()
+	}
+}
+
+// MaxBodySize returns a middleware that limits request bodies of the routes it is used on
+// to limit bytes, replacing the server-wide limit set by WithMaxRequestBodySize, 0 means no limit.
+// It must run before any handler reads the body, bindings then fail with binding.ErrBodyTooLarge
+// and answer 413. After BodyBuffer the buffered body is kept and checked against limit instead.
+func MaxBodySize(limit int64) HandlerFunc {
+	return func(c *Context) {
+		if body, ok := c.Request.Body.(*BufferedBody); ok {
+			if limit > 0 && int64(len(body.data)) > limit {
+				c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+				return
+			}
+			c.Next()
+			return
+		}
+		c.limitBody(limit)
+		c.Next()
+	}
+}
+
+// limitBody installs the body handlers read: the body as received capped at limit bytes
+// when limit > 0, decoded when it is sent with Content-Encoding gzip or deflate.
+// The decoded body no longer carries Content-Encoding and its length is unknown.
+func (c *Context) limitBody(limit int64) {
+	if c.rawBody == nil {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			return
+		}
+		c.rawBody = c.Request.Body
+		if c.server.options.DecompressRequestBody {
+			switch enc := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding"))); enc {
+			case "gzip", "x-gzip", "deflate":
+				c.bodyEncoding = enc
+				c.Request.Header.Del("Content-Encoding")
+				c.Request.ContentLength = -1
+			}
+		}
+	}
+
+	body := c.rawBody
+	if limit > 0 {
+		body = http.MaxBytesReader(c.Writer, body, limit)
+	}
+	if c.bodyEncoding != "" {
+		body = &decompressBody{
+			src:      &countingReader{ReadCloser: body},
+			encoding: c.bodyEncoding,
+			maxSize:  c.server.options.MaxDecompressedBodySize,
+			maxRatio: c.server.options.MaxDecompressionRatio,
+		}
+	}
+	c.Request.Body = body
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// minRatioCheck is the decoded size below which the compression ratio is not checked,
+// small bodies of repeated bytes legitimately compress far beyond any sensible ratio.
+const minRatioCheck = 1 << 20
+
+// decompressBody decodes a gzip or deflate body, failing with binding.ErrBodyTooLarge
+// once the decoded bytes exceed maxSize or maxRatio times the bytes read from src.
+type decompressBody struct {
+	src      *countingReader
+	r        io.ReadCloser
+	encoding string
+	n        int64
+	maxSize  int64
+	maxRatio int64
+	err      error
+}
+
+func (d *decompressBody) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	if d.r == nil {
+		if d.r, d.err = newDecompressor(d.encoding, d.src); d.err != nil {
+			return 0, d.err
+		}
+	}
+	if d.maxSize > 0 && int64(len(p)) > d.maxSize-d.n+1 {
+		p = p[:d.maxSize-d.n+1]
+	}
+
+	n, err := d.r.Read(p)
+	d.n += int64(n)
+	switch {
+	case d.maxSize > 0 && d.n > d.maxSize:
+		d.err = fmt.Errorf("%w: decompressed body exceeds %d bytes", binding.ErrBodyTooLarge, d.maxSize)
+		return 0, d.err
+	case d.maxRatio > 0 && d.n > minRatioCheck && d.n > d.maxRatio*d.src.n:
+		d.err = fmt.Errorf("%w: compression ratio exceeds %d", binding.ErrBodyTooLarge, d.maxRatio)
+		return 0, d.err
+	}
+	return n, err
+}
+
+func (d *decompressBody) Close() error {
+	if d.r != nil {
+		_ = d.r.Close()
+	}
+	return d.src.Close()
+}
+
+// newDecompressor returns the reader decoding r. Deflate bodies are accepted both
+// zlib wrapped, as HTTP specifies, and raw, as some clients send them.
+func newDecompressor(encoding string, r io.Reader) (io.ReadCloser, error) {
+	if encoding != "deflate" {
+		return gzip.NewReader(r)
+	}
+	br := bufio.NewReader(r)
+	if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,211 @@
+package gem
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/crazyfrankie/gem/binding"
+	"github.com/crazyfrankie/gem/config"
+)
+
+func compress(t *testing.T, encoding string, data []byte) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	var w interface {
+		Write([]byte) (int, error)
+		Close() error
+	}
+	if encoding == "gzip" {
+		w = gzip.NewWriter(&buf)
+	} else {
+		w, _ = flate.NewWriter(&buf, flate.BestCompression)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestBodyBuffer(t *testing.T) {
+	server := New()
+	server.Use(BodyBuffer(16))
+	server.POST("/", func(c *Context) {
+		// A middleware reading the whole body, like a signature check.
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			t.Error(err)
+		}
+		c.Next()
+	}, func(c *Context) {
+		var obj struct {
+			Name string `json:"name"`
+		}
+		if err := c.ShouldBindJSON(&obj); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		raw, _ := c.GetRawData()
+		c.String(http.StatusOK, obj.Name+" "+string(raw))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"gem"}`))
+	req.Header.Set("Content-Type", binding.MIMEJSON)
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != `gem {"name":"gem"}` {
+		t.Errorf("rewound body: %d %s", w.Code, w.Body.String())
+	}
+
+	for _, length := range []int64{17, -1} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"gemgemgem"}`))
+		req.ContentLength = length
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Content-Length %d: %d", length, w.Code)
+		}
+	}
+}
+
+func TestShouldBindBodyWith(t *testing.T) {
+	server := New()
+	server.POST("/", func(c *Context) {
+		var a struct {
+			Name string `json:"name"`
+		}
+		var b struct {
+			Age int `json:"age"`
+		}
+		if err := c.ShouldBindBodyWithJSON(&a); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.ShouldBindBodyWith(&b, binding.JSON); err != nil {
+			t.Fatal(err)
+		}
+		if len(c.Keys) != 0 {
+			t.Errorf("body cached in Keys: %v", c.Keys)
+		}
+		c.String(http.StatusOK, "%s %d", a.Name, b.Age)
+	})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"gem","age":3}`)))
+	if w.Code != http.StatusOK || w.Body.String() != "gem 3" {
+		t.Errorf("got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	server := New(WithMaxRequestBodySize(16))
+	handler := func(c *Context) {
+		var obj struct {
+			Name string `json:"name"`
+		}
+		if c.BindJSON(&obj) == nil {
+			c.String(http.StatusOK, obj.Name)
+		}
+	}
+	server.POST("/small", handler)
+	server.POST("/large", MaxBodySize(1024), handler)
+
+	body := `{"name":"a name longer than sixteen bytes"}`
+	for path, want := range map[string]int{"/small": http.StatusRequestEntityTooLarge, "/large": http.StatusOK} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", binding.MIMEJSON)
+		server.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: %d %s", path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestMaxBodySizeAfterBodyBuffer(t *testing.T) {
+	server := New()
+	server.POST("/", BodyBuffer(1024), MaxBodySize(16), func(c *Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.String(http.StatusOK, "%s", data)
+	})
+
+	for body, want := range map[string]int{`{"name":"gem"}`: http.StatusOK, `{"name":"a longer name"}`: http.StatusRequestEntityTooLarge} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		if w.Code != want || want == http.StatusOK && w.Body.String() != body {
+			t.Errorf("%s: %d %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRequestDecompression(t *testing.T) {
+	server := New(WithRequestDecompression(true))
+	server.POST("/", func(c *Context) {
+		var obj struct {
+			Name string `json:"name"`
+		}
+		if c.BindJSON(&obj) == nil {
+			c.String(http.StatusOK, obj.Name)
+		}
+	})
+
+	for _, encoding := range []string{"gzip", "deflate"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", compress(t, encoding, []byte(`{"name":"gem"}`)))
+		req.Header.Set("Content-Type", binding.MIMEJSON)
+		req.Header.Set("Content-Encoding", encoding)
+		server.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != "gem" {
+			t.Errorf("%s: %d %s", encoding, w.Code, w.Body.String())
+		}
+	}
+
+	// Whitespace is valid JSON, so the decoder keeps reading until a limit trips.
+	bomb := compress(t, "gzip", bytes.Repeat([]byte(" "), 5<<20)).Bytes()
+	for name, opt := range map[string]config.Option{
+		"compression ratio exceeds 100":           WithDecompressionLimits(0, 100),
+		"decompressed body exceeds 1048576 bytes": WithDecompressionLimits(1<<20, 0),
+	} {
+		server := New(WithRequestDecompression(true), opt)
+		var bindErr error
+		server.POST("/", func(c *Context) {
+			var obj struct{}
+			bindErr = c.BindJSON(&obj)
+		})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bomb))
+		req.Header.Set("Content-Type", binding.MIMEJSON)
+		req.Header.Set("Content-Encoding", "gzip")
+		server.ServeHTTP(w, req)
+		if w.Code != http.StatusRequestEntityTooLarge || !errors.Is(bindErr, binding.ErrBodyTooLarge) || !strings.Contains(bindErr.Error(), name) {
+			t.Errorf("%s: %d %v", name, w.Code, bindErr)
+		}
+	}
+
+	// Without the option the body reaches handlers as it was sent.
+	server = New()
+	server.POST("/", func(c *Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, "%s %d", c.GetHeader("Content-Encoding"), len(data))
+	})
+	body := compress(t, "gzip", []byte(`{"name":"gem"}`))
+	want := fmt.Sprintf("gzip %d", body.Len())
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Encoding", "gzip")
+	server.ServeHTTP(w, req)
+	if w.Body.String() != want {
+		t.Errorf("decompression disabled: %s, want %s", w.Body.String(), want)
+	}
+}
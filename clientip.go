@@ -0,0 +1,273 @@
+package gem
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Static CIDR lists of common CDNs and load balancers, for use with Server.SetTrustedProxies.
+// They are snapshots of the ranges published by each provider; deployments that depend on
+// them should refresh them from the provider regularly.
+var (
+	// CloudflareCIDRs lists the ranges published at https://www.cloudflare.com/ips/.
+	CloudflareCIDRs = []string{
+		"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22",
+		"141.101.64.0/18", "108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20",
+		"197.234.240.0/22", "198.41.128.0/17", "162.158.0.0/15", "104.16.0.0/13",
+		"104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22",
+		"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32", "2405:b500::/32",
+		"2405:8100::/32", "2a06:98c0::/29", "2c0f:f248::/32",
+	}
+
+	// FastlyCIDRs lists the ranges published at https://api.fastly.com/public-ip-list.
+	FastlyCIDRs = []string{
+		"23.235.32.0/20", "43.249.72.0/22", "103.244.50.0/24", "103.245.222.0/23",
+		"103.245.224.0/24", "104.156.80.0/20", "140.248.64.0/18", "140.248.128.0/17",
+		"146.75.0.0/17", "151.101.0.0/16", "157.52.64.0/18", "167.82.0.0/17",
+		"167.82.128.0/20", "167.82.160.0/20", "167.82.224.0/20", "172.111.64.0/18",
+		"185.31.16.0/22", "199.27.72.0/21", "199.232.0.0/16",
+		"2a04:4e40::/32", "2a04:4e42::/32",
+	}
+
+	// GoogleCloudLBCIDRs lists the source ranges of Google Cloud external HTTP(S) load balancers.
+	GoogleCloudLBCIDRs = []string{"35.191.0.0/16", "130.211.0.0/22"}
+
+	// PrivateCIDRs lists loopback and private network ranges, for proxies running next to the server.
+	PrivateCIDRs = []string{
+		"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+		"::1/128", "fc00::/7",
+	}
+)
+
+// defaultRemoteIPHeaders are the headers ClientIP consults, in order, by default.
+var defaultRemoteIPHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Real-IP"}
+
+// SetTrustedProxies sets the IP addresses and CIDR ranges of the proxies whose forwarding
+// headers are trusted by Context.ClientIP, Context.Scheme and Context.Host.
+// Nothing is trusted by default, pass nil to go back to that.
+func (server *Server) SetTrustedProxies(trustedProxies []string) error {
+	cidrs := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			if ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	server.trustedCIDRs = cidrs
+	return nil
+}
+
+// isTrustedProxy reports whether ip belongs to one of the trusted proxy ranges.
+func (server *Server) isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range server.trustedCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteIP parses the IP from Request.RemoteAddr, it is the address of the immediate peer.
+func (c *Context) RemoteIP() string {
+	ip, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	if err != nil {
+		return ""
+	}
+	return ip
+}
+
+// fromTrustedProxy reports whether the immediate peer is a trusted proxy.
+func (c *Context) fromTrustedProxy() bool {
+	return c.server != nil && c.server.isTrustedProxy(net.ParseIP(c.RemoteIP()))
+}
+
+// ClientIP returns the IP of the client. Forwarding headers listed in Server.RemoteIPHeaders are
+// only consulted when the immediate peer is a trusted proxy; they are then walked from the closest
+// hop outwards, and the first address that is not itself a trusted proxy is the client.
+func (c *Context) ClientIP() string {
+	remoteIP := c.RemoteIP()
+	if !c.fromTrustedProxy() {
+		return remoteIP
+	}
+
+	for _, name := range c.server.RemoteIPHeaders {
+		var hops []string
+		switch http.CanonicalHeaderKey(name) {
+		case "Forwarded":
+			for _, element := range c.forwarded() {
+				hops = append(hops, element["for"])
+			}
+		case "X-Forwarded-For":
+			hops = headerList(c.Request.Header, name)
+		default:
+			if v := strings.TrimSpace(c.Request.Header.Get(name)); v != "" {
+				hops = []string{v}
+			}
+		}
+		if ip, ok := c.firstUntrusted(hops); ok {
+			return ip
+		}
+	}
+	return remoteIP
+}
+
+// Scheme returns the scheme the client used, "http" or "https".
+// When the immediate peer is a trusted proxy, the proto of the Forwarded header or
+// X-Forwarded-Proto is honored.
+func (c *Context) Scheme() string {
+	if c.fromTrustedProxy() {
+		if element := c.edgeForwarded(); element["proto"] != "" {
+			return strings.ToLower(element["proto"])
+		}
+		if protos := headerList(c.Request.Header, "X-Forwarded-Proto"); len(protos) > 0 {
+			return strings.ToLower(protos[len(protos)-1])
+		}
+	}
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host returns the host the client requested. When the immediate peer is a trusted proxy,
+// the host of the Forwarded header or X-Forwarded-Host is honored.
+func (c *Context) Host() string {
+	if c.fromTrustedProxy() {
+		if element := c.edgeForwarded(); element["host"] != "" {
+			return element["host"]
+		}
+		if hosts := headerList(c.Request.Header, "X-Forwarded-Host"); len(hosts) > 0 {
+			return hosts[len(hosts)-1]
+		}
+	}
+	return c.Request.Host
+}
+
+// firstUntrusted walks hops from the closest one and returns the first address that is not a trusted proxy.
+// If every hop is trusted, the farthest one is returned.
+func (c *Context) firstUntrusted(hops []string) (string, bool) {
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseNode(hops[i])
+		if ip == nil {
+			return "", false
+		}
+		if i == 0 || !c.server.isTrustedProxy(ip) {
+			return ip.String(), true
+		}
+	}
+	return "", false
+}
+
+// edgeForwarded returns the Forwarded element added by the outermost trusted proxy,
+// which is the first element, walking from the closest hop, whose "for" is not trusted.
+func (c *Context) edgeForwarded() map[string]string {
+	if !c.usesHeader("Forwarded") {
+		return nil
+	}
+	elements := c.forwarded()
+	for i := len(elements) - 1; i >= 0; i-- {
+		if i == 0 || !c.server.isTrustedProxy(parseNode(elements[i]["for"])) {
+			return elements[i]
+		}
+	}
+	return nil
+}
+
+func (c *Context) usesHeader(name string) bool {
+	for _, h := range c.server.RemoteIPHeaders {
+		if http.CanonicalHeaderKey(h) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// forwarded parses the Forwarded header of RFC 7239 into its elements.
+// Parameter names are lower-cased and quoted values unquoted.
+func (c *Context) forwarded() []map[string]string {
+	var elements []map[string]string
+	for _, v := range c.Request.Header.Values("Forwarded") {
+		for _, element := range splitQuoted(v, ',') {
+			pairs := make(map[string]string)
+			for _, pair := range splitQuoted(element, ';') {
+				key, value, ok := strings.Cut(pair, "=")
+				if !ok {
+					continue
+				}
+				value = strings.TrimSpace(value)
+				if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+					value = strings.ReplaceAll(value[1:len(value)-1], `\`, "")
+				}
+				pairs[strings.ToLower(strings.TrimSpace(key))] = value
+			}
+			elements = append(elements, pairs)
+		}
+	}
+	return elements
+}
+
+// splitQuoted splits s at every sep that is not inside a quoted-string.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\' && quoted:
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// parseNode parses a node of a forwarding header, which may carry a port and IPv6 brackets,
+// such as "192.0.2.43:47011" or "[2001:db8:cafe::17]:4711".
+func parseNode(node string) net.IP {
+	node = strings.TrimSpace(node)
+	if strings.HasPrefix(node, "[") {
+		if end := strings.IndexByte(node, ']'); end > 0 {
+			return net.ParseIP(node[1:end])
+		}
+		return nil
+	}
+	if strings.Count(node, ":") == 1 {
+		node, _, _ = strings.Cut(node, ":")
+	}
+	return net.ParseIP(node)
+}
+
+// headerList returns the comma separated values of all name headers.
+func headerList(header http.Header, name string) []string {
+	var list []string
+	for _, v := range header.Values(name) {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
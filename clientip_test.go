@@ -0,0 +1,81 @@
+package gem
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newClientIPContext(t *testing.T, remoteAddr string, header map[string]string) *Context {
+	t.Helper()
+	server := New()
+	if err := server.SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return &Context{server: server, Request: req}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote string
+		header map[string]string
+		want   string
+	}{
+		{"untrusted peer ignores headers", "203.0.113.9:1234", map[string]string{"X-Forwarded-For": "1.1.1.1"}, "203.0.113.9"},
+		{"x-forwarded-for skips trusted hops", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "9.9.9.9, 1.1.1.1, 10.0.0.2"}, "1.1.1.1"},
+		{"x-real-ip", "192.168.1.1:1234", map[string]string{"X-Real-IP": "2.2.2.2"}, "2.2.2.2"},
+		{"forwarded with ipv6 and port", "10.0.0.1:1234", map[string]string{"Forwarded": `for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"`}, "2001:db8:cafe::17"},
+		{"forwarded wins over x-forwarded-for", "10.0.0.1:1234", map[string]string{"Forwarded": "for=3.3.3.3", "X-Forwarded-For": "4.4.4.4"}, "3.3.3.3"},
+		{"invalid header falls back", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "not-an-ip"}, "10.0.0.1"},
+		{"all hops trusted", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.1.1.1, 10.2.2.2"}, "10.1.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClientIPContext(t, tt.remote, tt.header)
+			if got := c.ClientIP(); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemeAndHost(t *testing.T) {
+	c := newClientIPContext(t, "203.0.113.9:1234", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.com"})
+	if c.Scheme() != "http" || c.Host() != "example.com" {
+		t.Fatalf("untrusted peer: got %s://%s", c.Scheme(), c.Host())
+	}
+
+	c = newClientIPContext(t, "10.0.0.1:1234", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"})
+	if c.Scheme() != "https" || c.Host() != "api.example.com" {
+		t.Fatalf("trusted peer: got %s://%s", c.Scheme(), c.Host())
+	}
+
+	c = newClientIPContext(t, "10.0.0.1:1234", map[string]string{"Forwarded": `for=1.2.3.4;proto=https;host="shop.example.com", for=10.0.0.5;proto=http;host=internal`})
+	if c.Scheme() != "https" || c.Host() != "shop.example.com" {
+		t.Fatalf("forwarded: got %s://%s", c.Scheme(), c.Host())
+	}
+
+	c = newClientIPContext(t, "203.0.113.9:1234", nil)
+	c.Request.TLS = &tls.ConnectionState{}
+	if c.Scheme() != "https" {
+		t.Fatalf("tls: got %s", c.Scheme())
+	}
+}
+
+func TestSetTrustedProxiesInvalid(t *testing.T) {
+	if err := New().SetTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("expected error for invalid CIDR")
+	}
+	for _, preset := range [][]string{CloudflareCIDRs, FastlyCIDRs, GoogleCloudLBCIDRs, PrivateCIDRs} {
+		if err := New().SetTrustedProxies(preset); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
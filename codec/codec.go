@@ -0,0 +1,97 @@
+// Package codec defines the serialization formats used by renders, bindings and error
+// responses, so that a Server can swap encoding/json, encoding/xml and yaml.v3 for other libraries.
+package codec
+
+import (
+	"io"
+	"sync"
+)
+
+// Names of the built-in codecs.
+const (
+	JSONName = "json"
+	XMLName  = "xml"
+	YAMLName = "yaml"
+)
+
+// Marshaler returns the encoding of v.
+type Marshaler interface {
+	Marshal(v any) ([]byte, error)
+}
+
+// Unmarshaler decodes data into the value pointed to by v.
+type Unmarshaler interface {
+	Unmarshal(data []byte, v any) error
+}
+
+// Encoder writes the encoding of values to a stream.
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder reads values from a stream.
+// A Decoder may also implement DisallowUnknownFields() and UseNumber(),
+// which the JSON binding calls when the matching options are set.
+type Decoder interface {
+	Decode(v any) error
+}
+
+// Codec serializes values in one format. Renders encode JSON and YAML with Marshal
+// and XML with an Encoder, bindings decode with a Decoder.
+type Codec interface {
+	Marshaler
+	Unmarshaler
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// Registry maps format names to codecs. The zero value, as well as a nil *Registry,
+// resolves the built-in names to JSON, XML and YAML.
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[string]Codec
+}
+
+// NewRegistry returns a Registry holding the built-in codecs.
+func NewRegistry() *Registry {
+	return &Registry{codecs: map[string]Codec{
+		JSONName: JSON,
+		XMLName:  XML,
+		YAMLName: YAML,
+	}}
+}
+
+// Register makes c the codec of name, replacing the codec registered before.
+func (r *Registry) Register(name string, c Codec) {
+	if c == nil {
+		panic("codec: codec can not be nil")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.codecs == nil {
+		r.codecs = make(map[string]Codec)
+	}
+	r.codecs[name] = c
+}
+
+// Get returns the codec of name, falling back to the built-in codec of that name.
+// It returns nil for unknown names.
+func (r *Registry) Get(name string) Codec {
+	if r != nil {
+		r.mu.RLock()
+		c, ok := r.codecs[name]
+		r.mu.RUnlock()
+		if ok {
+			return c
+		}
+	}
+	switch name {
+	case JSONName:
+		return JSON
+	case XMLName:
+		return XML
+	case YAMLName:
+		return YAML
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+)
+
+type upperJSON struct{ Codec }
+
+func (upperJSON) Marshal(v any) ([]byte, error) {
+	b, err := JSON.Marshal(v)
+	return bytes.ToUpper(b), err
+}
+
+func TestRegistry(t *testing.T) {
+	var zero *Registry
+	if zero.Get(JSONName) != JSON || zero.Get(XMLName) != XML || zero.Get(YAMLName) != YAML || zero.Get("toml") != nil {
+		t.Error("nil registry does not resolve the built-in codecs")
+	}
+
+	r := NewRegistry()
+	r.Register(JSONName, upperJSON{JSON})
+	b, err := r.Get(JSONName).Marshal(map[string]string{"a": "b"})
+	if err != nil || string(b) != `{"A":"B"}` {
+		t.Errorf("registered codec = %s, %v", b, err)
+	}
+	if r.Get(YAMLName) != YAML {
+		t.Error("registering json replaced yaml")
+	}
+}
+
+func TestStdCodecs(t *testing.T) {
+	type doc struct {
+		Name string `json:"name" xml:"name" yaml:"name"`
+	}
+	for name, c := range map[string]Codec{JSONName: JSON, XMLName: XML, YAMLName: YAML} {
+		var buf bytes.Buffer
+		if err := c.NewEncoder(&buf).Encode(doc{Name: "gem"}); err != nil {
+			t.Fatal(name, err)
+		}
+		var got doc
+		if err := c.NewDecoder(&buf).Decode(&got); err != nil || got.Name != "gem" {
+			t.Errorf("%s stream = %+v, %v", name, got, err)
+		}
+
+		data, err := c.Marshal(doc{Name: "gem"})
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		got = doc{}
+		if err := c.Unmarshal(data, &got); err != nil || got.Name != "gem" {
+			t.Errorf("%s = %+v, %v", name, got, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package codec
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The built-in codecs, backed by encoding/json, encoding/xml and gopkg.in/yaml.v3.
+var (
+	JSON Codec = jsonCodec{}
+	XML  Codec = xmlCodec{}
+	YAML Codec = yamlCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) NewEncoder(w io.Writer) Encoder     { return json.NewEncoder(w) }
+func (jsonCodec) NewDecoder(r io.Reader) Decoder     { return json.NewDecoder(r) }
+
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v any) ([]byte, error)      { return xml.Marshal(v) }
+func (xmlCodec) Unmarshal(data []byte, v any) error { return xml.Unmarshal(data, v) }
+func (xmlCodec) NewEncoder(w io.Writer) Encoder     { return xml.NewEncoder(w) }
+func (xmlCodec) NewDecoder(r io.Reader) Decoder     { return xml.NewDecoder(r) }
+
+type yamlCodec struct{}
+
+func (yamlCodec) Marshal(v any) ([]byte, error)      { return yaml.Marshal(v) }
+func (yamlCodec) Unmarshal(data []byte, v any) error { return yaml.Unmarshal(data, v) }
+func (yamlCodec) NewEncoder(w io.Writer) Encoder     { return yaml.NewEncoder(w) }
+func (yamlCodec) NewDecoder(r io.Reader) Decoder     { return yaml.NewDecoder(r) }
@@ -3,6 +3,9 @@ package config
 import (
 	"crypto/tls"
 	"time"
+
+	"github.com/crazyfrankie/gem/binding"
+	"github.com/crazyfrankie/gem/codec"
 )
 
 // Option is the only struct that can be used to set Options.
@@ -17,6 +20,10 @@ const (
 	defaultAddr             = ":9090"
 	defaultNetwork          = "tcp"
 	defaultBasePath         = "/"
+	defaultSecureJSONPrefix = "while(1);"
+
+	defaultMaxDecompressedBodySize = 32 << 20
+	defaultMaxDecompressionRatio   = 100
 )
 
 type Options struct {
@@ -34,6 +41,22 @@ type Options struct {
 	Addr                  string
 	BasePath              string
 	TLS                   *tls.Config
+
+	// MaxRequestBodySize caps the bytes read from request bodies, 0 means no limit.
+	MaxRequestBodySize int64
+	// DecompressRequestBody enables decoding gzip and deflate request bodies, it is off by default.
+	DecompressRequestBody bool
+	// MaxDecompressedBodySize and MaxDecompressionRatio bound decoded request bodies,
+	// 0 disables the check.
+	MaxDecompressedBodySize int64
+	MaxDecompressionRatio   int64
+
+	// JSONDecoding sets the options of the JSON binding, see Server.JSONDecoding.
+	JSONDecoding binding.JSONOptions
+	// Codecs provides the codecs of renders and bindings, nil means codec.NewRegistry().
+	Codecs *codec.Registry
+	// SecureJSONPrefix is written by SecureJSON before top-level arrays.
+	SecureJSONPrefix string
 }
 
 func (o *Options) Apply(opts []Option) {
@@ -57,6 +80,10 @@ func NewOptions(opts []Option) *Options {
 		Addr:                  defaultAddr,
 		Network:               defaultNetwork,
 		TLS:                   nil,
+
+		MaxDecompressedBodySize: defaultMaxDecompressedBodySize,
+		MaxDecompressionRatio:   defaultMaxDecompressionRatio,
+		SecureJSONPrefix:        defaultSecureJSONPrefix,
 	}
 	options.Apply(opts)
 	return options
@@ -1,16 +1,40 @@
 package gem
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"math"
 	"net/http"
 	"net/url"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/crazyfrankie/gem/binding"
+	"github.com/crazyfrankie/gem/codec"
 	"github.com/crazyfrankie/gem/render"
+	"github.com/crazyfrankie/gem/websocket"
+)
+
+// Content-Type MIME of the most common data formats.
+const (
+	MIMEJSON              = binding.MIMEJSON
+	MIMEHTML              = binding.MIMEHTML
+	MIMEXML               = binding.MIMEXML
+	MIMEXML2              = binding.MIMEXML2
+	MIMEPlain             = binding.MIMEPlain
+	MIMEPOSTForm          = binding.MIMEPOSTForm
+	MIMEMultipartPOSTForm = binding.MIMEMultipartPOSTForm
+	MIMEPROTOBUF          = binding.MIMEPROTOBUF
+	MIMEYAML              = binding.MIMEYAML
+	MIMEYAML2             = binding.MIMEYAML2
+	MIMEMSGPACK           = binding.MIMEMSGPACK
+	MIMEMSGPACK2          = binding.MIMEMSGPACK2
+	MIMECBOR              = binding.MIMECBOR
 )
 
 // ContextKey is the key that a Context returns itself for.
@@ -41,9 +65,24 @@ type Context struct {
 
 	// Keys is a key/value pair exclusively for the context of each request.
 	Keys map[string]any
+	// typedKeys holds the values stored through Key, protected by mu like Keys.
+	typedKeys map[any]any
 
 	// queryCache caches the query result from c.Request.URL.Query().
 	queryCache url.Values
+
+	// rawBody is the request body as received, wrapped by limitBody.
+	rawBody io.ReadCloser
+	// bodyEncoding is the Content-Encoding limitBody decodes the body from.
+	bodyEncoding string
+	// bodyBytes is the request body cached by ShouldBindBodyWith or BodyBuffer.
+	bodyBytes []byte
+
+	// renderingError is set while RenderError runs, so a failing error response is not reported again.
+	renderingError bool
+
+	// done is set once the request the Context was created for has completed.
+	done atomic.Bool
 }
 
 /***************************************/
@@ -61,17 +100,25 @@ func (c *Context) reset() {
 
 	c.fullPath = ""
 	c.Keys = nil
+	c.typedKeys = nil
 	c.queryCache = nil
+	c.rawBody = nil
+	c.bodyEncoding = ""
+	c.bodyBytes = nil
+	c.renderingError = false
+	c.done.Store(false)
 	*c.params = (*c.params)[:0]
 	*c.skippedNodes = (*c.skippedNodes)[:0]
 }
 
 // Copy returns a copy of the current context that can be safely used outside the request's scope.
 // This has to be used when the context has to be passed to a goroutine.
-func (c *Context) copy() *Context {
+// The copy shares the Request, so it is still canceled when the client goes away.
+func (c *Context) Copy() *Context {
 	ctx := &Context{
 		writemem: c.writemem,
 		Writer:   c.Writer,
+		Request:  c.Request,
 		server:   c.server,
 	}
 
@@ -80,6 +127,8 @@ func (c *Context) copy() *Context {
 	ctx.index = abortIndex
 	ctx.handlers = nil
 	ctx.fullPath = c.fullPath
+	ctx.Params = append(Params(nil), c.Params...)
+	ctx.bodyBytes = c.bodyBytes
 
 	cKeys := c.Keys
 	ctx.Keys = make(map[string]any, len(cKeys))
@@ -87,6 +136,12 @@ func (c *Context) copy() *Context {
 	for k, v := range c.Keys {
 		ctx.Keys[k] = v
 	}
+	if c.typedKeys != nil {
+		ctx.typedKeys = make(map[any]any, len(c.typedKeys))
+		for k, v := range c.typedKeys {
+			ctx.typedKeys[k] = v
+		}
+	}
 	c.mu.RUnlock()
 
 	return ctx
@@ -130,7 +185,7 @@ func (c *Context) Get(key string) (value any, exists bool) {
 
 // MustGet returns the value for the given key if exists, otherwise panic
 func (c *Context) MustGet(key string) any {
-	if value, exists := c.Keys[key]; exists {
+	if value, exists := c.Get(key); exists {
 		return value
 	}
 	panic("Key \"" + key + "\" does not exist")
@@ -238,25 +293,54 @@ func (c *Context) GetHeader(key string) string {
 }
 
 // GetRawData returns stream data.
+// The body cached by ShouldBindBodyWith or BodyBuffer is returned when present.
 func (c *Context) GetRawData() ([]byte, error) {
+	if c.bodyBytes != nil {
+		return c.bodyBytes, nil
+	}
 	if c.Request.Body == nil {
 		return nil, errors.New("cannot read nil body")
 	}
 	return io.ReadAll(c.Request.Body)
 }
 
+// ContentType returns the Content-Type header of the request without its parameters.
+func (c *Context) ContentType() string {
+	return filterFlags(c.GetHeader("Content-Type"))
+}
+
 // Bind Method
 // Methods of type Bind allow data to be bound to a structure.
 // Structured data such as JSON can simply be provided with a Bind method.
 // While Query Header data may need to be handled differently in different scenarios: it may be taken singly,
 // or it may be bound to a structure.
+//
+// The Bind* methods abort the request with HTTP 400 when binding fails, while the ShouldBind*
+// methods only return the error and leave the response to the caller.
+
+// Bind checks the Method and Content-Type to select a binding engine automatically,
+// Depending on the "Content-Type" header different bindings are used, for example:
+//
+//	"application/json" --> JSON binding
+//	"application/xml"  --> XML binding
+//
+// It aborts the request with HTTP 415 if no binding supports the Content-Type,
+// and with HTTP 400 if any other error occurs, the error is written by RenderError.
+func (c *Context) Bind(obj any) error {
+	if err := c.ShouldBind(obj); err != nil {
+		c.RenderError(bindingError(err))
+		return err
+	}
+
+	return nil
+}
 
 // MustBind binds the passed struct pointer using the specified binding engine.
-// It will abort the request with HTTP 400 if any error occurs.
-// See the binding package.
+// It will abort the request with HTTP 400 if any error occurs, the error is written
+// by RenderError, see DefaultErrorHandler for the body. See the binding package.
 func (c *Context) MustBind(obj any, bind binding.Binding) error {
-	if err := bind.Bind(c.Request, obj); err != nil {
-		c.AbortWithStatus(http.StatusBadRequest)
+	if err := c.ShouldBindWith(obj, bind); err != nil {
+		c.RenderError(bindingError(err))
 		return err
 	}
 
@@ -289,17 +373,247 @@ func (c *Context) BindHeader(obj any) error {
 	return c.MustBind(obj, binding.Header)
 }
 
+func (c *Context) BindProtoBuf(obj any) error {
+	return c.MustBind(obj, binding.ProtoBuf)
+}
+
+func (c *Context) BindMsgPack(obj any) error {
+	return c.MustBind(obj, binding.MsgPack)
+}
+
+func (c *Context) BindCBOR(obj any) error {
+	return c.MustBind(obj, binding.CBOR)
+}
+
 func (c *Context) BindUri(obj any) error {
+	if err := c.ShouldBindUri(obj); err != nil {
+		c.RenderError(bindingError(err))
+		return err
+	}
+
+	return nil
+}
+
+// ShouldBind checks the Method and Content-Type to select a binding engine automatically like Bind,
+// but it does not write anything to the response.
+// It returns an error wrapping binding.ErrUnsupportedMediaType if no binding supports the Content-Type.
+func (c *Context) ShouldBind(obj any) error {
+	b, err := c.defaultBinding()
+	if err != nil {
+		return err
+	}
+
+	return c.ShouldBindWith(obj, b)
+}
+
+// defaultBinding returns the binding selected by the Method and Content-Type.
+func (c *Context) defaultBinding() (binding.Binding, error) {
+	contentType := c.ContentType()
+	b := binding.Default(c.Request.Method, contentType)
+	if b == nil {
+		return nil, fmt.Errorf("%w: %q", binding.ErrUnsupportedMediaType, contentType)
+	}
+	return b, nil
+}
+
+// ShouldBindWith binds the passed struct pointer using the specified binding engine,
+// then validates it with binding.Validate.
+// A body buffered by the BodyBuffer middleware is rewound first, so it can be bound
+// even after an earlier handler has read it.
+// See the binding package.
+func (c *Context) ShouldBindWith(obj any, b binding.Binding) error {
+	if err := c.bindWith(obj, b); err != nil {
+		return err
+	}
+	return validate(obj, bindingSource(b))
+}
+
+// bindWith is ShouldBindWith without validation.
+func (c *Context) bindWith(obj any, b binding.Binding) error {
+	if body, ok := c.Request.Body.(*BufferedBody); ok {
+		body.Rewind()
+	}
+	if bb, ok := b.(binding.BindingBody); ok {
+		b = c.bodyBinding(bb)
+	}
+	return b.Bind(c.Request, obj)
+}
+
+// ShouldBindJSON is a shortcut for c.ShouldBindWith(obj, binding.JSON).
+func (c *Context) ShouldBindJSON(obj any) error {
+	return c.ShouldBindWith(obj, binding.JSON)
+}
+
+// ShouldBindPlain is a shortcut for c.ShouldBindWith(obj, binding.PLAIN).
+func (c *Context) ShouldBindPlain(obj any) error {
+	return c.ShouldBindWith(obj, binding.PLAIN)
+}
+
+// ShouldBindYAML is a shortcut for c.ShouldBindWith(obj, binding.YAML).
+func (c *Context) ShouldBindYAML(obj any) error {
+	return c.ShouldBindWith(obj, binding.YAML)
+}
+
+// ShouldBindXML is a shortcut for c.ShouldBindWith(obj, binding.XML).
+func (c *Context) ShouldBindXML(obj any) error {
+	return c.ShouldBindWith(obj, binding.XML)
+}
+
+// ShouldBindQuery is a shortcut for c.ShouldBindWith(obj, binding.Query).
+func (c *Context) ShouldBindQuery(obj any) error {
+	return c.ShouldBindWith(obj, binding.Query)
+}
+
+// ShouldBindHeader is a shortcut for c.ShouldBindWith(obj, binding.Header).
+func (c *Context) ShouldBindHeader(obj any) error {
+	return c.ShouldBindWith(obj, binding.Header)
+}
+
+// ShouldBindProtoBuf is a shortcut for c.ShouldBindWith(obj, binding.ProtoBuf).
+func (c *Context) ShouldBindProtoBuf(obj any) error {
+	return c.ShouldBindWith(obj, binding.ProtoBuf)
+}
+
+// ShouldBindMsgPack is a shortcut for c.ShouldBindWith(obj, binding.MsgPack).
+func (c *Context) ShouldBindMsgPack(obj any) error {
+	return c.ShouldBindWith(obj, binding.MsgPack)
+}
+
+// ShouldBindCBOR is a shortcut for c.ShouldBindWith(obj, binding.CBOR).
+func (c *Context) ShouldBindCBOR(obj any) error {
+	return c.ShouldBindWith(obj, binding.CBOR)
+}
+
+// ShouldBindBodyWith is similar with ShouldBindWith, but it stores the request
+// body into the context, and reuse when it is called again.
+//
+// NOTE: This method reads the body before binding. So you should use
+// ShouldBindWith for better performance if you need to call only once.
+func (c *Context) ShouldBindBodyWith(obj any, bb binding.BindingBody) (err error) {
+	body := c.bodyBytes
+	if body == nil {
+		body, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			return &binding.Error{Source: binding.SourceBody, Err: err}
+		}
+		c.bodyBytes = body
+	}
+
+	if err = c.bodyBinding(bb).BindBody(body, obj); err != nil {
+		return err
+	}
+	return validate(obj, binding.SourceBody)
+}
+
+// ShouldBindBodyWithJSON is a shortcut for c.ShouldBindBodyWith(obj, binding.JSON).
+func (c *Context) ShouldBindBodyWithJSON(obj any) error {
+	return c.ShouldBindBodyWith(obj, binding.JSON)
+}
+
+// ShouldBindBodyWithXML is a shortcut for c.ShouldBindBodyWith(obj, binding.XML).
+func (c *Context) ShouldBindBodyWithXML(obj any) error {
+	return c.ShouldBindBodyWith(obj, binding.XML)
+}
+
+// ShouldBindBodyWithYAML is a shortcut for c.ShouldBindBodyWith(obj, binding.YAML).
+func (c *Context) ShouldBindBodyWithYAML(obj any) error {
+	return c.ShouldBindBodyWith(obj, binding.YAML)
+}
+
+// ShouldBindUri binds the path params to the passed struct pointer and validates it.
+func (c *Context) ShouldBindUri(obj any) error {
+	if err := c.bindUri(obj); err != nil {
+		return err
+	}
+	return validate(obj, binding.SourceURI)
+}
+
+// bindUri is ShouldBindUri without validation.
+func (c *Context) bindUri(obj any) error {
 	m := make(map[string][]string, len(c.Params))
 	for _, v := range c.Params {
 		m[v.Key] = []string{v.Value}
 	}
-	if err := binding.Uri.BindingUri(m, obj); err != nil {
-		c.AbortWithStatus(http.StatusBadRequest)
+
+	return binding.Uri.BindingUri(m, obj)
+}
+
+// bodyBinding returns bb configured by the server: binding.JSON takes the options of
+// Server.JSONDecoding, and the JSON, XML and YAML bindings decode with the codecs of Server.Codecs.
+func (c *Context) bodyBinding(bb binding.BindingBody) binding.BindingBody {
+	if c.server == nil {
+		return bb
+	}
+	if bb == binding.JSON && c.server.JSONDecoding != (binding.JSONOptions{}) {
+		bb = binding.JSONWith(c.server.JSONDecoding)
+	}
+	switch name := bb.Name(); name {
+	case codec.JSONName, codec.XMLName, codec.YAMLName:
+		bb = binding.WithCodec(bb, c.codec(name))
+	}
+	return bb
+}
+
+// codec returns the codec Server.Codecs registers under name.
+func (c *Context) codec(name string) codec.Codec {
+	if c.server == nil {
+		return (*codec.Registry)(nil).Get(name)
+	}
+	return c.server.Codecs.Get(name)
+}
+
+// validate validates obj, the failures are reported as coming from source.
+func validate(obj any, source string) error {
+	err := binding.Validate(obj)
+	if errs, ok := err.(binding.Errors); ok {
+		for _, e := range errs {
+			e.Source = source
+		}
+	}
+	return err
+}
+
+// bindingSource returns the source of the values bound by b.
+func bindingSource(b binding.Binding) string {
+	switch b.Name() {
+	case "query":
+		return binding.SourceQuery
+	case "header":
+		return binding.SourceHeader
+	case "cookie":
+		return binding.SourceCookie
+	case "uri":
+		return binding.SourceURI
+	case "form", "form-urlencoded", "multipart/form-data":
+		return binding.SourceForm
+	}
+	return binding.SourceBody
+}
+
+// bindingError makes err a *binding.Error unless it already carries the failures of a bind,
+// so that DefaultErrorHandler reports it as a binding failure.
+func bindingError(err error) error {
+	var (
+		e    *binding.Error
+		errs binding.Errors
+	)
+	if errors.As(err, &e) || errors.As(err, &errs) {
 		return err
 	}
+	return &binding.Error{Err: err}
+}
 
-	return nil
+// bindErrorStatus returns the status a failed binding aborts the request with.
+func bindErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, binding.ErrInvalidTag):
+		return http.StatusInternalServerError
+	case errors.Is(err, binding.ErrUnsupportedMediaType), errors.Is(err, binding.ErrFileTypeNotAllowed):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, binding.ErrFileTooLarge), errors.Is(err, binding.ErrBodyTooLarge), errors.As(err, new(*http.MaxBytesError)):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
 }
 
 /*************************/
@@ -334,7 +648,16 @@ func (c *Context) Header(key, value string) {
 }
 
 // Render writes the response headers and calls render.Render to render data.
+// If the render fails before writing anything, the error goes to RenderError.
 func (c *Context) Render(code int, r render.Render) {
+	if cr, ok := r.(render.CodecRender); ok {
+		if rc := c.codec(cr.CodecName()); rc != nil {
+			r = cr.WithCodec(rc)
+		}
+	}
+	if cr, ok := r.(render.ContextRender); ok {
+		r = cr.WithContext(c.Request.Context())
+	}
 	c.Status(code)
 
 	if !bodyAllowedForStatus(code) {
@@ -344,6 +667,12 @@ func (c *Context) Render(code int, r render.Render) {
 	}
 
 	if err := r.Render(c.Writer); err != nil {
+		// Renders that fail before writing, such as HTML templates, leave room for an error response.
+		if !c.Writer.Written() && !c.renderingError {
+			c.Writer.Header().Del("Content-Type")
+			c.RenderError(err)
+			return
+		}
 		c.Abort()
 	}
 }
@@ -359,16 +688,118 @@ func (c *Context) JSON(code int, obj any) {
 	c.Render(code, render.JSON{Data: obj})
 }
 
+// IndentedJSON serializes the given struct as pretty JSON (indented + endlines) into the response body.
+// It also sets the Content-Type as "application/json".
+// WARNING: we recommend using this only for development purposes since printing pretty JSON is
+// more CPU and bandwidth consuming. Use Context.JSON() instead.
+func (c *Context) IndentedJSON(code int, obj any) {
+	c.Render(code, render.IndentedJSON{Data: obj})
+}
+
+// SecureJSON serializes the given struct as Secure JSON into the response body.
+// Top-level arrays are prefixed with Server.SecureJSONPrefix, "while(1);" by default,
+// to prevent JSON hijacking. It also sets the Content-Type as "application/json".
+func (c *Context) SecureJSON(code int, obj any) {
+	prefix := "while(1);"
+	if c.server != nil {
+		prefix = c.server.SecureJSONPrefix
+	}
+	c.Render(code, render.SecureJSON{Prefix: prefix, Data: obj})
+}
+
+// JSONP serializes the given struct as JSON into the response body, wrapped in a call of
+// the function named by the "callback" query parameter, with the Content-Type "application/javascript".
+// Without a callback it renders plain JSON. A callback that is not a JavaScript identifier
+// is rejected with 400 through RenderError.
+func (c *Context) JSONP(code int, obj any) {
+	callback, _ := c.GetQueryValue("callback")
+	if callback != "" && !render.ValidCallback(callback) {
+		c.RenderError(&binding.Error{Source: binding.SourceQuery, Field: "callback", Type: "string", Value: callback, Err: render.ErrInvalidCallback})
+		return
+	}
+	c.Render(code, render.JSONP{Callback: callback, Data: obj})
+}
+
+// AsciiJSON serializes the given struct as JSON into the response body with unicode to ASCII string.
+// It also sets the Content-Type as "application/json".
+func (c *Context) AsciiJSON(code int, obj any) {
+	c.Render(code, render.AsciiJSON{Data: obj})
+}
+
+// PureJSON serializes the given struct as JSON into the response body.
+// PureJSON, unlike JSON, does not replace special html characters with their unicode entities.
+func (c *Context) PureJSON(code int, obj any) {
+	c.Render(code, render.PureJSON{Data: obj})
+}
+
 // Data render a byte stream to client
 func (c *Context) Data(code int, contentType string, data []byte) {
 	c.Render(code, render.Data{ContentType: contentType, Data: data})
 }
 
+// DataFromReader writes the specified reader into the body stream and updates the HTTP code.
+// The reader is streamed to the client without being buffered. When it is an io.ReadSeeker and
+// code is 200, Range and If-Range requests are honored; set an ETag or Last-Modified header in
+// extraHeaders to make If-Range effective.
+func (c *Context) DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) {
+	if rs, ok := reader.(io.ReadSeeker); ok && code == http.StatusOK {
+		header := c.Writer.Header()
+		for k, v := range extraHeaders {
+			header.Set(k, v)
+		}
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		var modtime time.Time
+		if lm := header.Get("Last-Modified"); lm != "" {
+			modtime, _ = http.ParseTime(lm)
+		}
+		http.ServeContent(c.Writer, c.Request, "", modtime, rs)
+		return
+	}
+
+	c.Render(code, render.Reader{
+		Headers:       extraHeaders,
+		ContentType:   contentType,
+		ContentLength: contentLength,
+		Reader:        reader,
+	})
+}
+
+// File writes the specified file into the body stream in an efficient way.
+// Range, If-Range and conditional requests are handled by http.ServeFile.
+func (c *Context) File(filepath string) {
+	http.ServeFile(c.Writer, c.Request, filepath)
+}
+
+// FileFromFS writes the specified file from fsys into the body stream in an efficient way.
+func (c *Context) FileFromFS(filepath string, fsys fs.FS) {
+	http.ServeFileFS(c.Writer, c.Request, fsys, filepath)
+}
+
+// FileAttachment writes the specified file into the body stream in an efficient way
+// On the client side, the file will typically be downloaded with the given filename.
+// Non-ASCII filenames are encoded as described in RFC 6266.
+func (c *Context) FileAttachment(filepath, filename string) {
+	c.Writer.Header().Set("Content-Disposition", contentDisposition("attachment", filename))
+	http.ServeFile(c.Writer, c.Request, filepath)
+}
+
 // ProtoBuf serializes the given struct as ProtoBuf into the response body.\
 func (c *Context) ProtoBuf(code int, data any) {
 	c.Render(code, render.ProtoBuf{Data: data})
 }
 
+// MsgPack serializes the given struct as MessagePack into the response body.
+func (c *Context) MsgPack(code int, data any) {
+	c.Render(code, render.MsgPack{Data: data})
+}
+
+// CBOR serializes the given struct as CBOR into the response body.
+func (c *Context) CBOR(code int, data any) {
+	c.Render(code, render.CBOR{Data: data})
+}
+
 // YAML serializes the given struct as YAML into the response body.
 func (c *Context) YAML(code int, data []byte) {
 	c.Render(code, render.YAML{Data: data})
@@ -383,6 +814,184 @@ func (c *Context) Redirect(code int, location string) {
 	})
 }
 
+// SSEvent writes a Server-Sent Event into the body stream.
+func (c *Context) SSEvent(name string, message any) {
+	c.Render(-1, render.SSEvent{
+		Event: name,
+		Data:  message,
+	})
+}
+
+// LastEventID returns the ID of the last event the client received before reconnecting,
+// so that a Server-Sent Events handler can resume the stream after it, see ResumeSSE.
+func (c *Context) LastEventID() string {
+	return c.GetHeader("Last-Event-ID")
+}
+
+// Stream sends a streaming response and returns a boolean
+// indicates "Is client disconnected in middle of stream".
+// step is called repeatedly and the response is flushed after each call,
+// until step returns false or the client goes away.
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	w := c.Writer
+	clientGone := c.Request.Context().Done()
+	for {
+		select {
+		case <-clientGone:
+			return true
+		default:
+			keepOpen := step(w)
+			w.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// StreamSSE writes every event received from events as a Server-Sent Event until the
+// channel is closed, and returns a boolean indicates "Is client disconnected in middle of stream".
+// When keepAlive is positive, a comment is sent whenever the stream has been idle that long
+// so that proxies do not drop the connection.
+func (c *Context) StreamSSE(keepAlive time.Duration, events <-chan render.SSEvent) bool {
+	return c.streamSSE(keepAlive, nil, events)
+}
+
+// ResumeSSE is StreamSSE for clients that reconnect: when the request carries a Last-Event-ID,
+// the events replay returns for it, those the client missed, are sent before the ones received
+// from events. An event of events with the Id of a replayed one is not sent twice.
+func (c *Context) ResumeSSE(keepAlive time.Duration, replay func(lastEventID string) []render.SSEvent, events <-chan render.SSEvent) bool {
+	var missed []render.SSEvent
+	if id := c.LastEventID(); id != "" && replay != nil {
+		missed = replay(id)
+	}
+	return c.streamSSE(keepAlive, missed, events)
+}
+
+func (c *Context) streamSSE(keepAlive time.Duration, missed []render.SSEvent, events <-chan render.SSEvent) bool {
+	w := c.Writer
+	render.SSEvent{}.WriteContentType(w)
+	eventCodec := c.codec(codec.JSONName)
+
+	var replayed map[string]bool
+	for _, event := range missed {
+		if event.Codec == nil {
+			event.Codec = eventCodec
+		}
+		if err := event.Encode(w); err != nil {
+			return true
+		}
+		if event.Id != "" {
+			if replayed == nil {
+				replayed = make(map[string]bool, len(missed))
+			}
+			replayed[event.Id] = true
+		}
+	}
+	w.Flush()
+
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if keepAlive > 0 {
+		ticker = time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	clientGone := c.Request.Context().Done()
+	for {
+		select {
+		case <-clientGone:
+			return true
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if replayed[event.Id] {
+				delete(replayed, event.Id)
+				continue
+			}
+			if event.Codec == nil {
+				event.Codec = eventCodec
+			}
+			if err := event.Encode(w); err != nil {
+				return true
+			}
+			if ticker != nil {
+				ticker.Reset(keepAlive)
+			}
+		case <-tick:
+			if err := render.WriteSSEComment(w, "keepalive"); err != nil {
+				return true
+			}
+		}
+		w.Flush()
+	}
+}
+
+// Upgrade upgrades the request to the WebSocket protocol using websocket.DefaultUpgrader.
+// On failure an HTTP error has already been written to the client.
+func (c *Context) Upgrade() (*websocket.Conn, error) {
+	return c.UpgradeWith(websocket.DefaultUpgrader)
+}
+
+// UpgradeWith upgrades the request to the WebSocket protocol using the given upgrader.
+// The handler owns the returned connection and must close it.
+func (c *Context) UpgradeWith(upgrader *websocket.Upgrader) (*websocket.Conn, error) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		c.Abort()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// WithTimeout replaces the request context with one that is canceled after d,
+// and returns its cancel function, which should be deferred by the caller.
+// Everything that uses the Context as a context.Context afterwards observes the deadline.
+// Like WithValue, it replaces Request and must not run while other goroutines use the Context,
+// give them c.Copy() instead.
+func (c *Context) WithTimeout(d time.Duration) context.CancelFunc {
+	ctx, cancel := context.WithTimeout(c.requestContext(), d)
+	c.setRequestContext(ctx)
+	return cancel
+}
+
+// WithValue replaces the request context with one that carries val for key,
+// making it visible through Value and to handlers that read Request.Context().
+func (c *Context) WithValue(key, val any) {
+	c.setRequestContext(context.WithValue(c.requestContext(), key, val))
+}
+
+// requestContext returns the context of c.Request.
+func (c *Context) requestContext() context.Context {
+	if c.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
+// setRequestContext replaces the context of c.Request.
+// ctx must not be derived from c itself, the lookups of Value would otherwise never end.
+// Like every other write of Request, it is not synchronized with readers on other goroutines.
+func (c *Context) setRequestContext(ctx context.Context) {
+	c.Request = c.Request.WithContext(ctx)
+}
+
+// released reports whether the request c was created for has completed.
+// In debug mode it also warns about the call, since the Context may already serve another request.
+func (c *Context) released(method string) bool {
+	if !c.done.Load() {
+		return false
+	}
+	if IsDebugging() {
+		_, file, line, _ := runtime.Caller(2)
+		debugPrint("WARNING: Context.%s called at %s:%d after the request completed. "+
+			"Pass c.Copy() to goroutines that outlive the handler.\n", method, file, line)
+	}
+	return true
+}
+
 // hasRequestContext returns whether c.Request has Context and fallback.
 func (c *Context) hasRequestContext() bool {
 	hasFallback := c.server != nil && c.server.ContextWithFallback
@@ -390,28 +999,46 @@ func (c *Context) hasRequestContext() bool {
 	return hasFallback && hasRequestContext
 }
 
+// Deadline returns the deadline of the request context.
 func (c *Context) Deadline() (deadline time.Time, ok bool) {
-	if !c.hasRequestContext() {
+	if c.released("Deadline") || !c.hasRequestContext() {
 		return
 	}
-	return c.Request.Context().Deadline()
+	return c.requestContext().Deadline()
 }
 
+// Done returns the done channel of the request context, which is closed when the client
+// goes away or the handler returns. For a Context whose request has completed it returns
+// a closed channel.
 func (c *Context) Done() <-chan struct{} {
+	if c.released("Done") {
+		return closedChan
+	}
 	if !c.hasRequestContext() {
 		return nil
 	}
-	return c.Request.Context().Done()
+	return c.requestContext().Done()
 }
 
+// Err returns the error of the request context, context.Canceled once the request has completed.
 func (c *Context) Err() error {
+	if c.released("Err") {
+		return context.Canceled
+	}
 	if !c.hasRequestContext() {
 		return nil
 	}
-	return c.Request.Context().Err()
+	return c.requestContext().Err()
 }
 
+// Value returns the value associated with this context for key, or nil
+// if no value is associated with key. Keys, typed keys and the request context are consulted in turn.
+// Once the request has completed it always returns nil, so a Context that was kept
+// past its handler never reports values of the request it is reused for.
 func (c *Context) Value(key any) any {
+	if c.released("Value") {
+		return nil
+	}
 	if key == ContextRequestKey {
 		return c.Request
 	}
@@ -423,8 +1050,19 @@ func (c *Context) Value(key any) any {
 			return val
 		}
 	}
+	if typed, ok := key.(typedKey); ok {
+		if val, exists := c.getTyped(typed); exists {
+			return val
+		}
+	}
 	if !c.hasRequestContext() {
 		return nil
 	}
-	return c.Request.Context().Value(key)
+	return c.requestContext().Value(key)
+}
+
+var closedChan = make(chan struct{})
+
+func init() {
+	close(closedChan)
 }
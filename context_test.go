@@ -0,0 +1,538 @@
+package gem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crazyfrankie/gem/binding"
+	"github.com/crazyfrankie/gem/codec"
+	"github.com/crazyfrankie/gem/render"
+)
+
+func TestContextCancellation(t *testing.T) {
+	server := New()
+	var kept *Context
+	server.GET("/", func(c *Context) {
+		cancel := c.WithTimeout(time.Millisecond)
+		defer cancel()
+		c.WithValue("trace", "abc")
+
+		select {
+		case <-c.Done():
+		case <-time.After(time.Second):
+			t.Error("Done not closed after the timeout")
+		}
+		if !errors.Is(c.Err(), context.DeadlineExceeded) {
+			t.Errorf("Err = %v", c.Err())
+		}
+		if c.Value("trace") != "abc" || c.Request.Context().Value("trace") != "abc" {
+			t.Error("WithValue not visible")
+		}
+		kept = c
+	})
+
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	select {
+	case <-kept.Done():
+	default:
+		t.Fatal("Done of a completed Context must be closed")
+	}
+	if kept.Err() != context.Canceled || kept.Value("trace") != nil {
+		t.Fatalf("completed Context: Err = %v, Value = %v", kept.Err(), kept.Value("trace"))
+	}
+}
+
+func TestContextCopyOutlivesRequest(t *testing.T) {
+	server := New()
+	copied := make(chan *Context, 1)
+	server.GET("/:id", func(c *Context) {
+		c.Set("user", "gem")
+		copied <- c.Copy()
+	})
+
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/42", nil))
+
+	cp := <-copied
+	if cp.Err() != nil || cp.Value("user") != "gem" || cp.Params.ByName("id") != "42" {
+		t.Fatalf("copy: Err = %v, user = %v, id = %q", cp.Err(), cp.Value("user"), cp.Params.ByName("id"))
+	}
+}
+
+func TestShouldBind(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name" yaml:"name" form:"name"`
+	}
+	server := New()
+	var got user
+	var bindErr error
+	server.Any("/bind", func(c *Context) {
+		got = user{}
+		bindErr = c.ShouldBind(&got)
+	})
+	server.POST("/must", func(c *Context) {
+		var u user
+		if c.Bind(&u) == nil {
+			c.String(http.StatusOK, u.Name)
+		}
+	})
+
+	tests := []struct {
+		method, target, contentType, body string
+	}{
+		{http.MethodPost, "/bind", "application/json; charset=utf-8", `{"name":"gem"}`},
+		{http.MethodPost, "/bind", binding.MIMEXML2, `<user><name>gem</name></user>`},
+		{http.MethodPut, "/bind", binding.MIMEYAML, "name: gem"},
+		{http.MethodPost, "/bind", binding.MIMEPOSTForm, "name=gem"},
+		// GET requests bind the query string whatever their Content-Type.
+		{http.MethodGet, "/bind?name=gem", binding.MIMEJSON, ""},
+		{http.MethodGet, "/bind?name=gem", "", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		server.ServeHTTP(httptest.NewRecorder(), req)
+		if bindErr != nil || got.Name != "gem" {
+			t.Errorf("%s %s %q: %+v, %v", tt.method, tt.target, tt.contentType, got, bindErr)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/bind", strings.NewReader("name,gem"))
+	req.Header.Set("Content-Type", "text/csv")
+	server.ServeHTTP(httptest.NewRecorder(), req)
+	if !errors.Is(bindErr, binding.ErrUnsupportedMediaType) {
+		t.Errorf("text/csv: %v", bindErr)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/must", strings.NewReader("name,gem"))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Bind with text/csv: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMustBindErrorBody(t *testing.T) {
+	server := New()
+	server.GET("/", func(c *Context) {
+		var q struct {
+			Page int    `query:"page"`
+			Sort string `query:"sort" validate:"oneof=id name"`
+		}
+		if c.MustBind(&q, binding.Query) == nil {
+			c.Status(http.StatusOK)
+		}
+	})
+
+	for target, want := range map[string]string{
+		"/?page=x":         `{"code":400,"message":"Bad Request","errors":[{"source":"query","field":"page","type":"int","value":"x","message":"strconv.ParseInt: parsing \"x\": invalid syntax"}]}`,
+		"/?page=1&sort=at": `{"code":400,"message":"Bad Request","errors":[{"source":"query","field":"Sort","type":"string","value":"at","rule":"oneof","param":"id name","message":"field Sort failed on the 'oneof=id name' rule"}]}`,
+	} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusBadRequest || strings.TrimSpace(w.Body.String()) != want {
+			t.Errorf("%s: %d %s", target, w.Code, w.Body.String())
+		}
+	}
+
+	// A tag the binding can not apply is a server error and its details are not exposed.
+	server.GET("/invalid", func(c *Context) {
+		var q struct {
+			Page int `query:"page" validate:"min=one"`
+		}
+		c.MustBind(&q, binding.Query)
+	})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/invalid?page=1", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != `{"code":500,"message":"Internal Server Error"}` {
+		t.Errorf("invalid tag: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestServerJSONDecoding(t *testing.T) {
+	server := New(WithJSONDecoding(binding.JSONOptions{DisallowUnknownFields: true}))
+	server.POST("/", func(c *Context) {
+		var obj struct {
+			Name string `json:"name"`
+		}
+		if c.BindJSON(&obj) == nil {
+			c.String(http.StatusOK, obj.Name)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"gem","age":3}`))
+	req.Header.Set("Content-Type", MIMEJSON)
+	server.ServeHTTP(w, req)
+	want := `{"code":400,"message":"Bad Request","errors":[{"source":"body","field":"/age","message":"unknown field"}]}`
+	if w.Code != http.StatusBadRequest || strings.TrimSpace(w.Body.String()) != want {
+		t.Errorf("%d %s", w.Code, w.Body.String())
+	}
+}
+
+// countingCodec counts the values encoded and decoded through the JSON codec.
+type countingCodec struct {
+	codec.Codec
+	marshal, decode int
+}
+
+func (cc *countingCodec) Marshal(v any) ([]byte, error) {
+	cc.marshal++
+	return cc.Codec.Marshal(v)
+}
+
+func (cc *countingCodec) NewDecoder(r io.Reader) codec.Decoder {
+	cc.decode++
+	return cc.Codec.NewDecoder(r)
+}
+
+func TestServerCodecs(t *testing.T) {
+	cc := &countingCodec{Codec: codec.JSON}
+	codecs := codec.NewRegistry()
+	codecs.Register(codec.JSONName, cc)
+	server := New(WithCodecs(codecs))
+	server.POST("/", func(c *Context) {
+		var obj struct {
+			Name string `json:"name"`
+		}
+		if c.BindJSON(&obj) == nil {
+			c.Negotiate(http.StatusOK, Negotiate{Offered: []string{MIMEJSON}, Data: obj})
+		}
+	})
+
+	for _, body := range []string{`{"name":"gem"}`, `{"name":1}`} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", MIMEJSON)
+		server.ServeHTTP(w, req)
+	}
+	if cc.decode != 2 || cc.marshal != 2 {
+		t.Errorf("codec decoded %d and marshaled %d values, want 2 and 2", cc.decode, cc.marshal)
+	}
+}
+
+func TestJSONRenders(t *testing.T) {
+	server := New(WithSecureJSONPrefix(")]}',\n"))
+	data := map[string]any{"html": "<b>", "text": "héllo 😀"}
+	server.GET("/indented", func(c *Context) { c.IndentedJSON(http.StatusOK, map[string]int{"a": 1}) })
+	server.GET("/secure", func(c *Context) { c.SecureJSON(http.StatusOK, []int{1, 2}) })
+	server.GET("/jsonp", func(c *Context) { c.JSONP(http.StatusOK, map[string]int{"a": 1}) })
+	server.GET("/ascii", func(c *Context) { c.AsciiJSON(http.StatusOK, data) })
+	server.GET("/pure", func(c *Context) { c.PureJSON(http.StatusOK, data) })
+	server.GET("/json", func(c *Context) { c.JSON(http.StatusOK, data) })
+	server.GET("/ascii-long", func(c *Context) { c.AsciiJSON(http.StatusOK, strings.Repeat("é", 200)+"x😀") })
+	server.GET("/large", func(c *Context) { c.JSON(http.StatusOK, strings.Repeat("a", 100<<10)) })
+	server.GET("/unsupported", func(c *Context) { c.JSON(http.StatusOK, map[string]any{"ch": make(chan int)}) })
+
+	tests := []struct {
+		target, contentType, body string
+	}{
+		{"/indented", "application/json; charset=utf-8", "{\n    \"a\": 1\n}"},
+		{"/secure", "application/json; charset=utf-8", ")]}',\n[1,2]"},
+		{"/jsonp?callback=app.cb_1", "application/javascript; charset=utf-8", `/**/app.cb_1({"a":1});`},
+		{"/jsonp", "application/json; charset=utf-8", `{"a":1}`},
+		{"/ascii", "application/json", `{"html":"\u003cb\u003e","text":"h\u00e9llo \ud83d\ude00"}`},
+		{"/pure", "application/json; charset=utf-8", `{"html":"<b>","text":"héllo 😀"}`},
+		{"/json", "application/json; charset=utf-8", `{"html":"\u003cb\u003e","text":"héllo 😀"}`},
+		{"/ascii-long", "application/json", `"` + strings.Repeat(`\u00e9`, 200) + `x\ud83d\ude00"`},
+		{"/large", "application/json; charset=utf-8", `"` + strings.Repeat("a", 100<<10) + `"`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != tt.contentType || w.Body.String() != tt.body {
+			t.Errorf("%s: %d %q %s", tt.target, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+
+	// A value that fails to encode writes nothing, so the error response is sent instead.
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsupported", nil))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != `{"code":500,"message":"Internal Server Error"}` {
+		t.Errorf("unsupported value: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jsonp?callback=alert(1)", nil))
+	if w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), "alert(1)(") {
+		t.Errorf("invalid callback: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestStreamRenders(t *testing.T) {
+	type row struct {
+		Name   string    `csv:"name"`
+		Age    int       `json:"age"`
+		Born   time.Time `csv:"born"`
+		Secret string    `csv:"-"`
+		Nick   *string
+	}
+	born := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	server := New()
+	server.GET("/ndjson", func(c *Context) {
+		c.Render(http.StatusOK, render.NDJSON[int]{Seq: func(yield func(int) bool) {
+			for i := 1; i <= 3 && yield(i); i++ {
+			}
+		}})
+	})
+	server.GET("/array", func(c *Context) {
+		ch := make(chan string, 2)
+		ch <- "a"
+		ch <- "b"
+		close(ch)
+		c.Render(http.StatusOK, render.JSONArrayStream[string]{Chan: ch})
+	})
+	server.GET("/csv", func(c *Context) {
+		c.Render(http.StatusOK, render.CSV[*row]{Seq: func(yield func(*row) bool) {
+			yield(&row{Name: "ann, b", Age: 30, Born: born, Secret: "x"})
+		}})
+	})
+
+	server.GET("/csv-unexported", func(c *Context) {
+		type noExported struct{ name string }
+		c.Render(http.StatusOK, render.CSV[noExported]{Seq: func(yield func(noExported) bool) {
+			yield(noExported{name: "x"})
+		}})
+	})
+
+	tests := []struct {
+		target            string
+		code              int
+		contentType, body string
+	}{
+		{"/ndjson", http.StatusOK, "application/x-ndjson", "1\n2\n3\n"},
+		{"/array", http.StatusOK, "application/json; charset=utf-8", `["a","b"]`},
+		{"/csv", http.StatusOK, "text/csv; charset=utf-8", "name,age,born,Nick\n\"ann, b\",30,2000-01-02T03:04:05Z,\n"},
+		{"/csv-unexported", http.StatusInternalServerError, "application/json; charset=utf-8", `{"code":500,"message":"Internal Server Error"}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if w.Code != tt.code || w.Header().Get("Content-Type") != tt.contentType || w.Body.String() != tt.body {
+			t.Errorf("%s: %d %q %q", tt.target, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+
+	// A client going away stops the stream without closing the array.
+	ctx, cancel := context.WithCancel(context.Background())
+	server.GET("/disconnect", func(c *Context) {
+		c.Render(http.StatusOK, render.JSONArrayStream[int]{Seq: func(yield func(int) bool) {
+			for i := 0; yield(i); i++ {
+				if i == 1 {
+					cancel()
+				}
+			}
+		}})
+	})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/disconnect", nil).WithContext(ctx))
+	if w.Body.String() != "[0,1" {
+		t.Errorf("disconnect: %q", w.Body.String())
+	}
+
+	// A producer selecting on the request context is released when the client goes away.
+	ctx, cancel = context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	server.GET("/disconnect-chan", func(c *Context) {
+		ch := make(chan int)
+		done := c.Request.Context().Done()
+		go func() {
+			defer close(stopped)
+			for i := 0; ; i++ {
+				select {
+				case ch <- i:
+				case <-done:
+					return
+				}
+				if i == 0 {
+					cancel()
+				}
+			}
+		}()
+		c.Render(http.StatusOK, render.NDJSON[int]{Chan: ch})
+	})
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/disconnect-chan", nil).WithContext(ctx))
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("producer still blocked after the client went away")
+	}
+	if !strings.HasPrefix(w.Body.String(), "0\n") {
+		t.Errorf("disconnect chan: %q", w.Body.String())
+	}
+}
+
+func TestSSE(t *testing.T) {
+	var buf strings.Builder
+	event := render.SSEvent{Id: "1\n2", Event: "msg", Retry: 3000, Data: "a\r\nb\rc\n"}
+	if err := event.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id: 12\nevent: msg\nretry: 3000\ndata: a\ndata: b\ndata: c\ndata: \n\n"; buf.String() != want {
+		t.Errorf("framing: %q, want %q", buf.String(), want)
+	}
+
+	server := New()
+	server.GET("/event", func(c *Context) { c.SSEvent("user", H{"name": "gem"}) })
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/event", nil))
+	if w.Header().Get("Content-Type") != "text/event-stream" || w.Header().Get("Cache-Control") != "no-cache" ||
+		w.Body.String() != "event: user\ndata: {\"name\":\"gem\"}\n\n" {
+		t.Errorf("SSEvent: %q %q", w.Header(), w.Body.String())
+	}
+
+	server.GET("/keepalive", func(c *Context) {
+		events := make(chan render.SSEvent)
+		go func() {
+			time.Sleep(60 * time.Millisecond)
+			events <- render.SSEvent{Data: "late"}
+			close(events)
+		}()
+		if c.StreamSSE(10*time.Millisecond, events) {
+			t.Error("keepalive stream reported a disconnect")
+		}
+	})
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/keepalive", nil))
+	if body := w.Body.String(); !strings.HasPrefix(body, ": keepalive\n\n") || !strings.HasSuffix(body, "data: late\n\n") {
+		t.Errorf("keepalive: %q", body)
+	}
+
+	server.GET("/resume", func(c *Context) {
+		events := make(chan render.SSEvent, 2)
+		events <- render.SSEvent{Id: "3", Data: "three"}
+		events <- render.SSEvent{Id: "4", Data: "four"}
+		close(events)
+		c.ResumeSSE(0, func(lastEventID string) []render.SSEvent {
+			if lastEventID != "1" {
+				t.Errorf("Last-Event-ID = %q", lastEventID)
+			}
+			return []render.SSEvent{{Id: "2", Data: "two"}, {Id: "3", Data: "three"}}
+		}, events)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/resume", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if want := "id: 2\ndata: two\n\nid: 3\ndata: three\n\nid: 4\ndata: four\n\n"; w.Body.String() != want {
+		t.Errorf("resume: %q, want %q", w.Body.String(), want)
+	}
+
+	// Both streams stop once the client goes away.
+	ctx, cancel := context.WithCancel(context.Background())
+	var gone bool
+	server.GET("/stream", func(c *Context) {
+		n := 0
+		gone = c.Stream(func(w io.Writer) bool {
+			n++
+			if n == 2 {
+				cancel()
+			}
+			fmt.Fprintf(w, "%d", n)
+			return true
+		})
+	})
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil).WithContext(ctx))
+	if !gone || w.Body.String() != "12" {
+		t.Errorf("Stream: gone = %v, body %q", gone, w.Body.String())
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	server.GET("/sse-gone", func(c *Context) {
+		events := make(chan render.SSEvent)
+		cancel()
+		gone = c.StreamSSE(time.Hour, events)
+	})
+	gone = false
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/sse-gone", nil).WithContext(ctx))
+	if !gone {
+		t.Error("StreamSSE did not report the disconnect")
+	}
+}
+
+func TestDataFromReader(t *testing.T) {
+	const data = "hello world"
+	server := New()
+	server.GET("/seek", func(c *Context) {
+		c.DataFromReader(http.StatusOK, int64(len(data)), "text/plain", strings.NewReader(data),
+			map[string]string{"ETag": `"v1"`})
+	})
+	server.GET("/stream", func(c *Context) {
+		c.DataFromReader(http.StatusOK, int64(len(data)), "text/plain", io.MultiReader(strings.NewReader(data)), nil)
+	})
+
+	tests := []struct {
+		target, rangeHeader, ifRange string
+		code                         int
+		body, contentRange           string
+	}{
+		{"/seek", "", "", http.StatusOK, data, ""},
+		{"/seek", "bytes=6-", "", http.StatusPartialContent, "world", "bytes 6-10/11"},
+		{"/seek", "bytes=0-4", `"v1"`, http.StatusPartialContent, "hello", "bytes 0-4/11"},
+		// A stale If-Range gets the whole current representation.
+		{"/seek", "bytes=0-4", `"v0"`, http.StatusOK, data, ""},
+		{"/seek", "bytes=20-", "", http.StatusRequestedRangeNotSatisfiable, "", "bytes */11"},
+		// Readers that can not seek are streamed whole.
+		{"/stream", "bytes=0-4", "", http.StatusOK, data, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.rangeHeader != "" {
+			req.Header.Set("Range", tt.rangeHeader)
+		}
+		if tt.ifRange != "" {
+			req.Header.Set("If-Range", tt.ifRange)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Header().Get("Content-Range") != tt.contentRange ||
+			(tt.body != "" && w.Body.String() != tt.body) {
+			t.Errorf("%s Range %q If-Range %q: %d %q %q", tt.target, tt.rangeHeader, tt.ifRange,
+				w.Code, w.Header().Get("Content-Range"), w.Body.String())
+		}
+	}
+}
+
+func TestFileAttachment(t *testing.T) {
+	for filename, want := range map[string]string{
+		"report.pdf": `attachment; filename="report.pdf"`,
+		`a"b\c.txt`:  `attachment; filename="a\"b\\c.txt"`,
+		"résumé.pdf": `attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`,
+		"100%.txt":   `attachment; filename="100_.txt"; filename*=UTF-8''100%25.txt`,
+		"报告 1.txt":   `attachment; filename="__ 1.txt"; filename*=UTF-8''%E6%8A%A5%E5%91%8A%201.txt`,
+	} {
+		if got := contentDisposition("attachment", filename); got != want {
+			t.Errorf("contentDisposition(%q) = %s, want %s", filename, got, want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("hello world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server := New()
+	server.GET("/download", func(c *Context) { c.FileAttachment(path, "données.txt") })
+	req := httptest.NewRequest(http.MethodGet, "/download", nil)
+	req.Header.Set("Range", "bytes=0-4")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "hello" ||
+		w.Header().Get("Content-Disposition") != `attachment; filename="donn_es.txt"; filename*=UTF-8''donn%C3%A9es.txt` {
+		t.Errorf("FileAttachment: %d %q %q", w.Code, w.Header().Get("Content-Disposition"), w.Body.String())
+	}
+}
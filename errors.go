@@ -0,0 +1,129 @@
+package gem
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/crazyfrankie/gem/binding"
+	"github.com/crazyfrankie/gem/gerrors"
+)
+
+// ErrorHandlerFunc writes the response for an error that ended the request.
+type ErrorHandlerFunc func(c *Context, err error)
+
+// errorBody is the response body written by DefaultErrorHandler.
+type errorBody struct {
+	Code    int32             `json:"code"`
+	Message string            `json:"message"`
+	Extra   map[string]string `json:"extra,omitempty"`
+	Errors  []fieldErrorBody  `json:"errors,omitempty"`
+}
+
+// fieldErrorBody describes a binding.Error in the body written by DefaultErrorHandler.
+type fieldErrorBody struct {
+	Source  string `json:"source,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Value   any    `json:"value,omitempty"`
+	Rule    string `json:"rule,omitempty"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// DefaultErrorHandler is used when Server.ErrorHandler is nil.
+// A gerrors.BizErrorIface is written as a JSON body carrying its code, message and extra,
+// with the status derived from the code: 4xx and 5xx codes are used as is, longer codes
+// such as 40004 or 500123 use their leading three digits when those are a 4xx or 5xx status,
+// and any other code answers 500.
+//
+// A *binding.Error or binding.Errors is written with the status of the failed bind,
+// usually 400, or 500 without details for binding.ErrInvalidTag, and lists every failure under "errors":
+//
+//	{"code":400,"message":"Bad Request","errors":[{"source":"query","field":"page","type":"int","value":"x","message":"..."}]}
+//
+// Any other error is reported as 500 without exposing its message.
+func DefaultErrorHandler(c *Context, err error) {
+	if errs, ok := bindingErrors(err); ok {
+		status := bindErrorStatus(err)
+		if status >= http.StatusInternalServerError {
+			// A tag the binding can not apply is a bug of the server, not of the request.
+			c.AbortWithJSON(status, errorBody{Code: int32(status), Message: http.StatusText(status)})
+			return
+		}
+		body := errorBody{
+			Code:    int32(status),
+			Message: http.StatusText(status),
+			Errors:  make([]fieldErrorBody, len(errs)),
+		}
+		for i, e := range errs {
+			fe := fieldErrorBody{
+				Source:  e.Source,
+				Field:   e.Field,
+				Type:    e.Type,
+				Value:   e.Value,
+				Message: e.Err.Error(),
+			}
+			var ve binding.FieldError
+			if errors.As(e.Err, &ve) {
+				fe.Rule, fe.Param = ve.Rule, ve.Param
+			}
+			body.Errors[i] = fe
+		}
+		c.AbortWithJSON(status, body)
+		return
+	}
+
+	bizErr, ok := gerrors.FromBizStatusError(err)
+	if !ok {
+		c.AbortWithJSON(http.StatusInternalServerError, errorBody{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+		})
+		return
+	}
+
+	c.AbortWithJSON(bizStatus(bizErr.BizStatusCode()), errorBody{
+		Code:    bizErr.BizStatusCode(),
+		Message: bizErr.BizMessage(),
+		Extra:   bizErr.BizExtra(),
+	})
+}
+
+// bindingErrors returns the failures carried by a binding error.
+func bindingErrors(err error) (binding.Errors, bool) {
+	var errs binding.Errors
+	if errors.As(err, &errs) {
+		return errs, true
+	}
+	var e *binding.Error
+	if errors.As(err, &e) {
+		return binding.Errors{e}, true
+	}
+	return nil, false
+}
+
+// bizStatus returns the HTTP status of a business error code: a code in the 4xx or 5xx range,
+// or starting with such a status like 40401, gives that status, any other code gives 500
+// so that an error never answers as a success or a redirect.
+func bizStatus(code int32) int {
+	for code >= 1000 {
+		code /= 10
+	}
+	if code >= 400 && code <= 599 {
+		return int(code)
+	}
+	return http.StatusInternalServerError
+}
+
+// RenderError aborts the request and writes err through Server.ErrorHandler,
+// or DefaultErrorHandler when none is set.
+func (c *Context) RenderError(err error) {
+	handler := DefaultErrorHandler
+	if c.server != nil && c.server.ErrorHandler != nil {
+		handler = c.server.ErrorHandler
+	}
+	c.renderingError = true
+	handler(c, err)
+	c.renderingError = false
+	c.Abort()
+}
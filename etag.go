@@ -0,0 +1,314 @@
+package gem
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag returns a middleware that answers conditional GET and HEAD requests.
+// It buffers 200 responses and hashes their body into an ETag, weak if weak is set,
+// unless the handler already set one with SetETag. When If-None-Match or If-Modified-Since
+// show that the client has the current representation, the body is dropped and 304 is sent.
+//
+// Responses that are flushed, such as streams and server-sent events, or hijacked are passed
+// through unbuffered and get no ETag.
+func ETag(weak bool) HandlerFunc {
+	return func(c *Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &etagWriter{ResponseWriter: c.Writer, status: defaultStatus, hash: sha256.New()}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+		w.finish(c, weak)
+	}
+}
+
+// SetETag sets the ETag response header to tag, quoting it if needed and marking it weak if weak is set.
+func (c *Context) SetETag(tag string, weak bool) {
+	c.Header("ETag", formatETag(tag, weak))
+}
+
+// SetLastModified sets the Last-Modified response header to t.
+func (c *Context) SetLastModified(t time.Time) {
+	if t.IsZero() {
+		c.Header("Last-Modified", "")
+		return
+	}
+	c.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// CheckPreconditions evaluates the conditional request headers against the ETag and
+// Last-Modified response headers set so far, following RFC 9110 section 13.2.2.
+// It returns true if the handler should go on. Otherwise it aborts with 304 Not Modified,
+// for GET and HEAD requests the client has an up to date copy for, or with
+// 412 Precondition Failed, for instance when If-Match does not match the current ETag
+// of a resource a PUT or PATCH is about to change.
+//
+// Call it after SetETag or SetLastModified and before doing expensive work:
+//
+//	c.SetETag(doc.Version, false)
+//	if !c.CheckPreconditions() {
+//		return
+//	}
+func (c *Context) CheckPreconditions() bool {
+	status := c.preconditionStatus()
+	if status == 0 {
+		return true
+	}
+	removeBodyHeaders(c.Writer.Header())
+	c.AbortWithStatus(status)
+	return false
+}
+
+// preconditionStatus returns 304 or 412 when a precondition of the request fails, 0 otherwise.
+func (c *Context) preconditionStatus() int {
+	header := c.Writer.Header()
+	etag := header.Get("ETag")
+	lastModified, _ := http.ParseTime(header.Get("Last-Modified"))
+	getOrHead := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+
+	if im := c.Request.Header.Get("If-Match"); im != "" {
+		if !etagMatch(im, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := c.Request.Header.Get("If-Unmodified-Since"); ius != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && lastModified.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if inm := c.Request.Header.Get("If-None-Match"); inm != "" {
+		if etagMatch(inm, etag, true) {
+			if getOrHead {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims := c.Request.Header.Get("If-Modified-Since"); ims != "" && getOrHead && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// removeBodyHeaders removes the headers describing a body from a response sent without one,
+// such as 304 or a 412 that replaces the body the handler wrote.
+func removeBodyHeaders(h http.Header) {
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+}
+
+func formatETag(tag string, weak bool) string {
+	if tag == "" {
+		return ""
+	}
+	if !strings.HasPrefix(tag, `"`) && !strings.HasPrefix(tag, `W/"`) {
+		tag = `"` + tag + `"`
+	}
+	if weak && !strings.HasPrefix(tag, "W/") {
+		tag = "W/" + tag
+	}
+	return tag
+}
+
+// etagMatch reports whether the list of entity tags of an If-Match or If-None-Match header
+// contains etag, comparing weakly if weak is set. "*" matches any current ETag.
+func etagMatch(list, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	for list != "" {
+		list = strings.TrimLeft(list, " \t,")
+		if list == "" {
+			break
+		}
+		tag, rest := scanETag(list)
+		if tag == "" {
+			// Skip malformed entries up to the next separator.
+			if i := strings.IndexByte(list, ','); i >= 0 {
+				list = list[i+1:]
+				continue
+			}
+			break
+		}
+		if etagEqual(tag, etag, weak) {
+			return true
+		}
+		list = rest
+	}
+	return false
+}
+
+// scanETag returns the entity tag at the start of s and what follows it.
+func scanETag(s string) (tag, rest string) {
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+	if len(s)-start < 2 || s[start] != '"' {
+		return "", ""
+	}
+	for i := start + 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return s[:i+1], s[i+1:]
+		case c == 0x21 || c >= 0x23 && c <= 0x7E || c >= 0x80:
+		default:
+			return "", ""
+		}
+	}
+	return "", ""
+}
+
+// etagEqual compares two entity tags, strong comparison fails if either of them is weak.
+func etagEqual(a, b string, weak bool) bool {
+	if weak {
+		return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+	}
+	return a == b && !strings.HasPrefix(a, "W/")
+}
+
+// etagWriter holds the response of ETag back until the handlers are done,
+// hashing the body while it is written.
+type etagWriter struct {
+	ResponseWriter
+	buf         bytes.Buffer
+	hash        hash.Hash
+	status      int
+	written     bool
+	passthrough bool
+}
+
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *etagWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.written = true
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	w.written = true
+	w.hash.Write(data)
+	return w.buf.Write(data)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.Write([]byte(s))
+}
+
+func (w *etagWriter) Status() int {
+	if w.passthrough {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *etagWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	if !w.written {
+		return noWritten
+	}
+	return w.buf.Len()
+}
+
+func (w *etagWriter) Written() bool {
+	if w.passthrough {
+		return w.ResponseWriter.Written()
+	}
+	return w.written
+}
+
+// Flush gives up buffering: what was written so far is sent and the rest goes straight through.
+func (w *etagWriter) Flush() {
+	w.release()
+	w.ResponseWriter.Flush()
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.release()
+	return w.ResponseWriter.Hijack()
+}
+
+// release sends the buffered response to the underlying writer and switches to pass-through.
+func (w *etagWriter) release() {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.written {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf = bytes.Buffer{}
+}
+
+// finish sets the ETag of a buffered 200 response and sends it, or sends 304 without a body
+// when the client's copy is current and 412 without a body when a precondition fails.
+func (w *etagWriter) finish(c *Context, weak bool) {
+	if w.passthrough {
+		return
+	}
+	// Only a full 200 response carries the representation the tag names, partial 206 responses
+	// and other statuses are passed through unchanged.
+	if w.status == http.StatusOK {
+		header := w.Header()
+		if header.Get("ETag") == "" && w.written {
+			sum := w.hash.Sum(nil)
+			header.Set("ETag", formatETag(base64.RawURLEncoding.EncodeToString(sum[:16]), weak))
+		}
+		if status := c.preconditionStatus(); status != 0 {
+			w.status = status
+			w.buf.Reset()
+			removeBodyHeaders(header)
+		}
+	}
+	if !bodyAllowedForStatus(w.status) {
+		w.buf.Reset()
+		removeBodyHeaders(w.Header())
+	}
+	w.release()
+}
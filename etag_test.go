@@ -0,0 +1,143 @@
+package gem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestETag(t *testing.T) {
+	server := New()
+	server.Use(ETag(false))
+	server.GET("/doc", func(c *Context) { c.String(http.StatusOK, "hello") })
+	server.GET("/missing", func(c *Context) { c.String(http.StatusNotFound, "missing") })
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/doc", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "hello" || len(etag) < 3 || etag[0] != '"' {
+		t.Fatalf("first request: %d %q %q", w.Code, etag, w.Body.String())
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		code        int
+		body        string
+	}{
+		{etag, http.StatusNotModified, ""},
+		{`"other", W/` + etag, http.StatusNotModified, ""},
+		{"*", http.StatusNotModified, ""},
+		{`"other"`, http.StatusOK, "hello"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/doc", nil)
+		req.Header.Set("If-None-Match", tt.ifNoneMatch)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Body.String() != tt.body || w.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: %d %q %q", tt.ifNoneMatch, w.Code, w.Header().Get("ETag"), w.Body.String())
+		}
+		if tt.code == http.StatusNotModified && w.Header().Get("Content-Type") != "" {
+			t.Errorf("If-None-Match %s: 304 with Content-Type %q", tt.ifNoneMatch, w.Header().Get("Content-Type"))
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("If-None-Match", "*")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Body.String() != "missing" || w.Header().Get("ETag") != "" {
+		t.Errorf("error response: %d %q %q", w.Code, w.Header().Get("ETag"), w.Body.String())
+	}
+
+	// Partial content is another representation than the full body and gets no tag of its own.
+	server.GET("/range", func(c *Context) {
+		http.ServeContent(c.Writer, c.Request, "", time.Time{}, strings.NewReader("hello world"))
+	})
+	req = httptest.NewRequest(http.MethodGet, "/range", nil)
+	req.Header.Set("Range", "bytes=0-4")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "hello" || w.Header().Get("ETag") != "" {
+		t.Errorf("range: %d %q %q", w.Code, w.Header().Get("ETag"), w.Body.String())
+	}
+
+	// A failed precondition drops the body together with the headers describing it.
+	server.GET("/sized", func(c *Context) {
+		c.SetLastModified(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+		c.Header("Content-Length", "5")
+		c.String(http.StatusOK, "hello")
+	})
+	for header, value := range map[string]string{
+		"If-Match":            `"other"`,
+		"If-Unmodified-Since": time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/sized", nil)
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != http.StatusPreconditionFailed || w.Body.Len() != 0 ||
+			w.Header().Get("Content-Length") != "" || w.Header().Get("Content-Type") != "" {
+			t.Errorf("%s: %d %v %q", header, w.Code, w.Header(), w.Body.String())
+		}
+	}
+
+	weak := New()
+	weak.Use(ETag(true))
+	weak.GET("/doc", func(c *Context) { c.String(http.StatusOK, "hello") })
+	w = httptest.NewRecorder()
+	weak.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/doc", nil))
+	if got := w.Header().Get("ETag"); got != "W/"+etag {
+		t.Errorf("weak ETag: %q, want W/%s", got, etag)
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var worked int
+	handler := func(c *Context) {
+		c.SetETag("v2", false)
+		c.SetLastModified(modified)
+		if !c.CheckPreconditions() {
+			return
+		}
+		worked++
+		c.String(http.StatusOK, "done")
+	}
+	server := New()
+	server.GET("/doc", handler)
+	server.PUT("/doc", handler)
+
+	tests := []struct {
+		method, header, value string
+		code                  int
+	}{
+		{http.MethodGet, "", "", http.StatusOK},
+		{http.MethodGet, "If-None-Match", `"v1", "v2"`, http.StatusNotModified},
+		{http.MethodGet, "If-None-Match", `"v1"`, http.StatusOK},
+		{http.MethodGet, "If-Modified-Since", modified.Format(http.TimeFormat), http.StatusNotModified},
+		{http.MethodGet, "If-Modified-Since", modified.Add(-time.Hour).Format(http.TimeFormat), http.StatusOK},
+		{http.MethodPut, "If-Match", `"v2"`, http.StatusOK},
+		{http.MethodPut, "If-Match", `W/"v2"`, http.StatusPreconditionFailed},
+		{http.MethodPut, "If-Match", `"v1"`, http.StatusPreconditionFailed},
+		{http.MethodPut, "If-None-Match", "*", http.StatusPreconditionFailed},
+		{http.MethodPut, "If-Unmodified-Since", modified.Add(-time.Hour).Format(http.TimeFormat), http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		worked = 0
+		req := httptest.NewRequest(tt.method, "/doc", nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != tt.code || (worked == 1) != (tt.code == http.StatusOK) {
+			t.Errorf("%s %s: %s: %d, handler ran %d times", tt.method, tt.header, tt.value, w.Code, worked)
+		}
+		if w.Header().Get("ETag") != `"v2"` {
+			t.Errorf("%s %s: %s: ETag %q", tt.method, tt.header, tt.value, w.Header().Get("ETag"))
+		}
+	}
+}
@@ -3,6 +3,7 @@ package gem
 import (
 	"context"
 	"errors"
+	"html/template"
 	"log"
 	"net"
 	"net/http"
@@ -16,7 +17,10 @@ import (
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 
+	"github.com/crazyfrankie/gem/binding"
+	"github.com/crazyfrankie/gem/codec"
 	"github.com/crazyfrankie/gem/config"
+	"github.com/crazyfrankie/gem/render"
 )
 
 const escapedColon = "\\:"
@@ -39,9 +43,38 @@ type Server struct {
 	options *config.Options
 
 	// ContextWithFallback enable fallback Context.Deadline(), Context.Done(), Context.Err() and Context.Value() when Context.Request.Context() is not nil.
+	// It is enabled by New, so a Context passed as a context.Context observes client disconnects.
 	ContextWithFallback bool
-	maxParams           uint16
-	maxSections         uint16
+
+	// RemoteIPHeaders lists the headers Context.ClientIP consults, in order, when the
+	// immediate peer is a trusted proxy. See SetTrustedProxies.
+	RemoteIPHeaders []string
+	trustedCIDRs    []*net.IPNet
+
+	// ErrorHandler writes the response of errors passed to Context.RenderError,
+	// such as those returned by handlers built with Handle. Nil means DefaultErrorHandler.
+	ErrorHandler ErrorHandlerFunc
+
+	// JSONDecoding sets the options of the JSON binding selected by Bind, BindJSON, BindAll
+	// and the other binding methods of Context. Bind with binding.JSONWith to change them per call.
+	// New sets it from WithJSONDecoding.
+	JSONDecoding binding.JSONOptions
+
+	// Codecs provides the JSON, XML and YAML codecs used by renders, bindings and
+	// error responses. New sets it from WithCodecs or to codec.NewRegistry(), register a codec to replace one.
+	Codecs *codec.Registry
+
+	// SecureJSONPrefix is written by Context.SecureJSON before top-level arrays.
+	// New sets it from WithSecureJSONPrefix, "while(1);" by default.
+	SecureJSONPrefix string
+
+	// HTMLRender produces the renders of Context.HTML, it is set by LoadHTMLGlob and LoadHTMLFS.
+	HTMLRender render.HTMLRender
+	funcMap    template.FuncMap
+	delims     render.Delims
+
+	maxParams   uint16
+	maxSections uint16
 }
 
 func New(opts ...config.Option) *Server {
@@ -52,8 +85,16 @@ func New(opts ...config.Option) *Server {
 			basePath: "/",
 			root:     true,
 		},
-		trees:   make(methodTrees, 0, 9),
-		options: options,
+		trees:               make(methodTrees, 0, 9),
+		options:             options,
+		ContextWithFallback: true,
+		RemoteIPHeaders:     append([]string(nil), defaultRemoteIPHeaders...),
+		JSONDecoding:        options.JSONDecoding,
+		Codecs:              options.Codecs,
+		SecureJSONPrefix:    options.SecureJSONPrefix,
+	}
+	if server.Codecs == nil {
+		server.Codecs = codec.NewRegistry()
 	}
 	server.RouterGroup.server = server
 	server.ctxPool.New = func() any {
@@ -164,12 +205,18 @@ func (server *Server) ServeHTTP(writer http.ResponseWriter, request *http.Reques
 	ctx.Request = request
 	// Ensure that the context of the current request is clean
 	ctx.reset()
+	ctx.limitBody(server.options.MaxRequestBodySize)
 
 	// Find Route
 	// Execute business logic
 	server.handleHTTPRequest(ctx)
 
-	server.ctxPool.Put(ctx)
+	ctx.done.Store(true)
+	// In debug mode contexts are not reused, so a Context kept past its handler
+	// keeps reporting the completed request instead of silently serving another one.
+	if !IsDebugging() {
+		server.ctxPool.Put(ctx)
+	}
 }
 
 func (server *Server) addRoute(method string, path string, handlers HandlersChain) {
@@ -0,0 +1,128 @@
+package gem
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/crazyfrankie/gem/gerrors"
+)
+
+// Validator is implemented by request types that check themselves once bound.
+type Validator interface {
+	Validate() error
+}
+
+// RouteInfo represents a request route's specification which contains method and path and its handler.
+type RouteInfo struct {
+	Method      string
+	Path        string
+	Handler     string
+	HandlerFunc HandlerFunc
+	// Request and Response are the types bound and rendered by a handler built with Handle, nil otherwise.
+	Request  reflect.Type
+	Response reflect.Type
+}
+
+// RoutesInfo defines a RouteInfo slice.
+type RoutesInfo []RouteInfo
+
+// Routes returns a slice of registered routes, including some useful information, such as:
+// the http method, path, handler name and the types of handlers built with Handle.
+func (server *Server) Routes() (routes RoutesInfo) {
+	for _, tree := range server.trees {
+		routes = iterate(tree.method, routes, tree.root)
+	}
+	return routes
+}
+
+func iterate(method string, routes RoutesInfo, root *node) RoutesInfo {
+	if len(root.handlers) > 0 {
+		handlerFunc := root.handlers[len(root.handlers)-1]
+		info := RouteInfo{
+			Method:      method,
+			Path:        root.fullPath,
+			Handler:     nameOfFunction(handlerFunc),
+			HandlerFunc: handlerFunc,
+		}
+		if meta, ok := handleMetas.Load(funcKey(handlerFunc)); ok {
+			info.Request = meta.(handleMeta).req
+			info.Response = meta.(handleMeta).resp
+		}
+		routes = append(routes, info)
+	}
+	for _, child := range root.children {
+		routes = iterate(method, routes, child)
+	}
+	return routes
+}
+
+// handleMeta records the types of a handler built with Handle.
+type handleMeta struct {
+	req, resp reflect.Type
+}
+
+// handleMetas maps the closures returned by Handle to their handleMeta.
+var handleMetas sync.Map
+
+// funcKey identifies a func value by its closure, so every handler built with Handle
+// is told apart even when instantiations share their code.
+func funcKey(h HandlerFunc) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&h))
+}
+
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// Handle adapts fn to a HandlerFunc, it is meant to be called when routes are registered.
+// The request is bound into a new Req with Context.ShouldBindAll, which validates it once
+// with binding.Validate, then by its Validate method if it implements Validator.
+// The Resp returned by fn is rendered with a 200 through content negotiation,
+// a nil Resp answers 204.
+//
+// Binding and validation failures are reported as 400 (415 for unsupported media types)
+// with the field-level body of binding errors, errors returned by Validate and fn
+// go to Server.ErrorHandler, see DefaultErrorHandler.
+func Handle[Req, Resp any](fn func(ctx context.Context, req *Req) (*Resp, error)) HandlerFunc {
+	assert(fn != nil, "handle func can not be nil")
+
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+	respType := reflect.TypeOf((*Resp)(nil)).Elem()
+	offered := []string{MIMEJSON, MIMEXML, MIMEYAML}
+	if reflect.PointerTo(respType).Implements(protoMessageType) {
+		offered = append(offered, MIMEPROTOBUF)
+	}
+
+	h := HandlerFunc(func(c *Context) {
+		req := new(Req)
+		if err := c.ShouldBindAll(req); err != nil {
+			c.RenderError(bindingError(err))
+			return
+		}
+		if v, ok := any(req).(Validator); ok {
+			if err := v.Validate(); err != nil {
+				if _, ok := gerrors.FromBizStatusError(err); !ok {
+					err = gerrors.NewBizError(http.StatusBadRequest, err.Error())
+				}
+				c.RenderError(err)
+				return
+			}
+		}
+
+		resp, err := fn(c, req)
+		if err != nil {
+			c.RenderError(err)
+			return
+		}
+		if resp == nil {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.Negotiate(http.StatusOK, Negotiate{Offered: offered, Data: resp})
+	})
+	handleMetas.Store(funcKey(h), handleMeta{req: reqType, resp: respType})
+	return h
+}
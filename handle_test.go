@@ -0,0 +1,108 @@
+package gem
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/crazyfrankie/gem/gerrors"
+)
+
+type createUserReq struct {
+	Name string `json:"name"`
+}
+
+func (r *createUserReq) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type createUserResp struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestHandle(t *testing.T) {
+	server := New()
+	server.POST("/users", Handle(func(ctx context.Context, req *createUserReq) (*createUserResp, error) {
+		if req.Name == "taken" {
+			return nil, gerrors.NewBizErrorWithExtra(40901, "name taken", map[string]string{"name": req.Name})
+		}
+		if req.Name == "boom" {
+			return nil, errors.New("database is down")
+		}
+		return &createUserResp{ID: 1, Name: req.Name}, nil
+	}))
+
+	tests := []struct {
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{`{"name":"gem"}`, http.StatusOK, `{"id":1,"name":"gem"}`},
+		{`{"name":""}`, http.StatusBadRequest, `{"code":400,"message":"name is required"}`},
+		{`{"name":`, http.StatusBadRequest, `"code":400`},
+		{`{"name":"taken"}`, http.StatusConflict, `{"code":40901,"message":"name taken","extra":{"name":"taken"}}`},
+		{`{"name":"boom"}`, http.StatusInternalServerError, `{"code":500,"message":"Internal Server Error"}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", MIMEJSON)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+			t.Errorf("%s: got %d %s, want %d %s", tt.body, w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+		}
+	}
+}
+
+func TestHandleRouteInfo(t *testing.T) {
+	server := New()
+	server.POST("/users", Handle(func(ctx context.Context, req *createUserReq) (*createUserResp, error) {
+		return nil, nil
+	}))
+	server.GET("/ping", func(c *Context) {})
+
+	for _, route := range server.Routes() {
+		switch route.Path {
+		case "/users":
+			if route.Request != reflect.TypeOf(createUserReq{}) || route.Response != reflect.TypeOf(createUserResp{}) {
+				t.Errorf("/users: got %v, %v", route.Request, route.Response)
+			}
+		case "/ping":
+			if route.Request != nil || route.Response != nil {
+				t.Errorf("/ping: got %v, %v", route.Request, route.Response)
+			}
+		default:
+			t.Errorf("unexpected route %s %s", route.Method, route.Path)
+		}
+	}
+}
+
+func TestBizStatus(t *testing.T) {
+	for code, want := range map[int32]int{404: 404, 40004: 400, 404001: 404, 50001: 500, 599: 599, 1001: 500, 0: 500, 9999999: 500} {
+		if got := bizStatus(code); got != want {
+			t.Errorf("bizStatus(%d) = %d, want %d", code, got, want)
+		}
+	}
+	// Codes outside 400-599 never answer as a success, a redirect or an informational response.
+	for _, code := range []int32{100, 101, 200, 204, 302, 304, 399, 20001} {
+		if got := bizStatus(code); got != http.StatusInternalServerError {
+			t.Errorf("bizStatus(%d) = %d, want 500", code, got)
+		}
+	}
+
+	server := New()
+	server.GET("/", func(c *Context) { c.RenderError(gerrors.NewBizError(204, "gone quiet")) })
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), `"code":204`) {
+		t.Errorf("biz code 204: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,64 @@
+package gem
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+
+	"github.com/crazyfrankie/gem/render"
+)
+
+// Delims sets the template left and right delims used by the LoadHTML methods called after it.
+func (server *Server) Delims(left, right string) *Server {
+	server.delims = render.Delims{Left: left, Right: right}
+	return server
+}
+
+// SetFuncMap sets the FuncMap used by the LoadHTML methods called after it.
+func (server *Server) SetFuncMap(funcMap template.FuncMap) {
+	server.funcMap = funcMap
+}
+
+// LoadHTMLGlob loads the HTML files matched by the glob pattern, see render.HTMLTemplates
+// for layouts and partials. In debug mode the files are parsed again whenever they change.
+// It panics if the templates fail to parse.
+func (server *Server) LoadHTMLGlob(pattern string) {
+	server.loadHTML(nil, []string{pattern})
+}
+
+// LoadHTMLFS loads the HTML files of fsys matched by patterns, like LoadHTMLGlob.
+func (server *Server) LoadHTMLFS(fsys fs.FS, patterns ...string) {
+	assert(fsys != nil, "template file system can not be nil")
+	server.loadHTML(fsys, patterns)
+}
+
+func (server *Server) loadHTML(fsys fs.FS, patterns []string) {
+	assert(len(patterns) > 0, "at least one template pattern is required")
+	templates := &render.HTMLTemplates{
+		FS:       fsys,
+		Patterns: patterns,
+		FuncMap:  server.funcMap,
+		Delims:   server.delims,
+		Reload:   IsDebugging(),
+	}
+	if err := templates.Load(); err != nil {
+		panic(err)
+	}
+	debugPrint("[WARNING] Templates are parsed again on change in debug mode, this is not meant for production.\n")
+	server.HTMLRender = templates
+}
+
+var errNoHTMLRender = errors.New("gem: no HTML templates loaded, call LoadHTMLGlob or LoadHTMLFS")
+
+// HTML renders the HTTP template specified by its file name.
+// It also updates the HTTP code and sets the Content-Type as "text/html".
+// The template is executed before anything is written, so execution errors
+// go to RenderError instead of producing a half-written page.
+// See http://golang.org/doc/articles/wiki/
+func (c *Context) HTML(code int, name string, obj any) {
+	if c.server == nil || c.server.HTMLRender == nil {
+		c.RenderError(errNoHTMLRender)
+		return
+	}
+	c.Render(code, c.server.HTMLRender.Instance(name, obj))
+}
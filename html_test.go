@@ -0,0 +1,128 @@
+package gem
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestHTMLLayouts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/layouts/base.html":  {Data: []byte(`<title>[[block "title" .]]gem[[end]]</title><main>[[block "content" .]][[end]]</main>`)},
+		"views/partials/user.html": {Data: []byte(`<b>[[upper .]]</b>`)},
+		"views/index.html":         {Data: []byte(`[[template "base.html" .]][[define "content"]]Hi [[template "user.html" .]][[end]]`)},
+		"views/about.html":         {Data: []byte(`[[template "base.html" .]][[define "title"]]About[[end]][[define "content"]]About [[.]][[end]]`)},
+		"views/broken.html":        {Data: []byte(`[[.Missing.Field]]`)},
+	}
+	server := New()
+	server.Delims("[[", "]]")
+	server.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	server.LoadHTMLFS(fsys, "views/*.html", "views/*/*.html")
+	server.GET("/:page", func(c *Context) {
+		c.HTML(http.StatusOK, c.Params.ByName("page")+".html", "<gem>")
+	})
+
+	tests := []struct {
+		page string
+		code int
+		body string
+	}{
+		{"index", http.StatusOK, `<title>gem</title><main>Hi <b>&lt;GEM&gt;</b></main>`},
+		{"about", http.StatusOK, `<title>About</title><main>About &lt;gem&gt;</main>`},
+		{"broken", http.StatusInternalServerError, `{"code":500,"message":"Internal Server Error"}`},
+		{"missing", http.StatusInternalServerError, `{"code":500,"message":"Internal Server Error"}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tt.page, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s: %d %s", tt.page, w.Code, w.Body.String())
+		}
+		if tt.code == http.StatusOK && w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+			t.Errorf("%s: Content-Type %q", tt.page, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestHTMLReload(t *testing.T) {
+	SetMode(DebugMode)
+	defer SetMode(ReleaseMode)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(file, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server := New()
+	server.LoadHTMLGlob(filepath.Join(dir, "*.html"))
+	server.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index.html", nil) })
+
+	get := func() string {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Body.String()
+	}
+	if body := get(); body != "v1" {
+		t.Fatalf("body = %q", body)
+	}
+	if err := os.WriteFile(file, []byte("v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if body := get(); body != "v2" {
+		t.Errorf("body after change = %q", body)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if body := get(); body != "v2" {
+				t.Errorf("concurrent body = %q", body)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHTMLDuplicateNames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/index.html":          {Data: []byte(`index`)},
+		"views/admin/index.html":    {Data: []byte(`admin`)},
+		"views/partials/index.html": {Data: []byte(`partial`)},
+	}
+	for _, patterns := range [][]string{
+		{"views/*.html", "views/admin/*.html"},
+		{"views/*.html", "views/partials/*.html"},
+	} {
+		func() {
+			defer func() {
+				if err, _ := recover().(error); err == nil || !strings.Contains(err.Error(), `both named "index.html"`) {
+					t.Errorf("%v: recovered %v", patterns, err)
+				}
+			}()
+			New().LoadHTMLFS(fsys, patterns...)
+		}()
+	}
+
+	// A file matched by several patterns is parsed once.
+	server := New()
+	server.LoadHTMLFS(fsys, "views/*.html", "views/index.html")
+	server.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index.html", nil) })
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Body.String() != "index" {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
@@ -0,0 +1,174 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Vectors from RFC 8949 Appendix A.
+func TestDecodeVectors(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want any
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1bffffffffffffffff", uint64(math.MaxUint64)},
+		{"20", int64(-1)},
+		{"3903e7", int64(-1000)},
+		{"f93c00", 1.0},
+		{"f97bff", 65504.0},
+		{"f90001", 5.960464477539063e-08},
+		{"fa47c35000", 100000.0},
+		{"fb3ff199999999999a", 1.1},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"f7", nil},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"6449455446", "IETF"},
+		{"62225c", `"\`},
+		{"83010203", []any{int64(1), int64(2), int64(3)}},
+		{"8301820203820405", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"a201020304", map[any]any{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[string]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9f018202039f0405ffff", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"bf61610161629f0203ffff", map[string]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+		{"c074323031332d30332d32315432303a30343a30305a", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+		{"c11a514b67b0", time.Unix(1363896240, 0)},
+		{"c1fb41d452d9ec200000", time.Unix(1363896240, 500000000)},
+		{"d74401020304", []byte{1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.hex)
+		var got any
+		if err := Unmarshal(data, &got); err != nil {
+			t.Errorf("%s: %v", tt.hex, err)
+			continue
+		}
+		if gt, ok := got.(time.Time); ok {
+			if !gt.Equal(tt.want.(time.Time)) {
+				t.Errorf("%s: got %v, want %v", tt.hex, got, tt.want)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.hex, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeVectors(t *testing.T) {
+	tests := []struct {
+		v   any
+		hex string
+	}{
+		{0, "00"},
+		{24, "1818"},
+		{uint64(math.MaxUint64), "1bffffffffffffffff"},
+		{-1000, "3903e7"},
+		{1.1, "fb3ff199999999999a"},
+		{true, "f5"},
+		{nil, "f6"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"IETF", "6449455446"},
+		{[]int{1, 2, 3}, "83010203"},
+		{map[string]any{"b": []int{2, 3}, "a": 1}, "a26161016162820203"},
+		{time.Unix(1363896240, 0), "c11a514b67b0"},
+	}
+	for _, tt := range tests {
+		got, err := Marshal(tt.v)
+		if err != nil || hex.EncodeToString(got) != tt.hex {
+			t.Errorf("%v: got %x, %v, want %s", tt.v, got, err, tt.hex)
+		}
+	}
+}
+
+type Embedded struct {
+	Level int `cbor:"level"`
+}
+
+type record struct {
+	Embedded
+	Name    string            `cbor:"name"`
+	Alias   string            `json:"alias,omitempty"`
+	Scores  []float32         `cbor:"scores"`
+	Meta    map[string]string `cbor:"meta"`
+	When    time.Time         `cbor:"when"`
+	Parent  *record           `cbor:"parent"`
+	Ignored string            `cbor:"-"`
+	Fixed   [2]uint8          `cbor:"fixed"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := record{
+		Embedded: Embedded{Level: -3},
+		Name:     "gem",
+		Scores:   []float32{1.5, 2},
+		Meta:     map[string]string{"k": "v"},
+		When:     time.Unix(1700000000, 0),
+		Parent:   &record{Name: "root"},
+		Ignored:  "x",
+		Fixed:    [2]uint8{7, 8},
+	}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("alias")) || bytes.Contains(data, []byte("Ignored")) {
+		t.Fatal("omitempty or - field encoded")
+	}
+
+	var out record
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	in.Ignored = ""
+	if !out.When.Equal(in.When) {
+		t.Fatalf("time: got %v", out.When)
+	}
+	out.When, in.When = time.Time{}, time.Time{}
+	out.Parent.When, in.Parent.When = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("got  %+v\nwant %+v", out, in)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		hex string
+		v   any
+	}{
+		{"", new(any)},
+		{"1a0000", new(any)},             // truncated argument
+		{"9bffffffffffffffff", new(any)}, // length beyond data
+		{"0001", new(any)},               // trailing data
+		{"62c328", new(string)},          // invalid UTF-8
+		{"1901f4", new(int8)},            // overflow
+		{"20", new(uint)},                // negative into unsigned
+		{"6161", new(int)},               // type mismatch
+		{"ff", new(any)},                 // stray break
+		{"1c", new(any)},                 // reserved additional information
+	}
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.hex)
+		if err := Unmarshal(data, tt.v); err == nil {
+			t.Errorf("%s: expected error", tt.hex)
+		}
+	}
+
+	deep := bytes.Repeat([]byte{0x81}, maxDepth+10)
+	deep = append(deep, 0x00)
+	var v any
+	if err := Unmarshal(deep, &v); err == nil {
+		t.Error("expected max depth error")
+	}
+}
@@ -0,0 +1,596 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+	"unicode/utf8"
+
+	"github.com/crazyfrankie/gem/internal/structfields"
+)
+
+var errShortData = errors.New("cbor: unexpected end of data")
+
+// Unmarshal decodes the CBOR data into v, which must be a non-nil pointer.
+// Into an interface value, integers decode as int64 (uint64 beyond its range), floats as
+// float64, maps as map[string]any, or map[any]any when a key is not a string.
+// Tags other than 0 and 1 are ignored and their content decoded.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cbor: Unmarshal(non-pointer %T)", v)
+	}
+	d := decoder{data: data}
+	if err := d.decode(rv.Elem(), 0); err != nil {
+		return err
+	}
+	if d.off != len(d.data) {
+		return errors.New("cbor: trailing data after the top-level value")
+	}
+	return nil
+}
+
+type kind uint8
+
+const (
+	kindNil kind = iota
+	kindBool
+	kindUint
+	kindNegInt
+	kindFloat
+	kindBytes
+	kindText
+	kindArray
+	kindMap
+	kindTag
+	kindBreak
+)
+
+var kindNames = [...]string{"null", "bool", "unsigned integer", "negative integer", "float",
+	"byte string", "text string", "array", "map", "tag", "break"}
+
+func (k kind) String() string {
+	return kindNames[k]
+}
+
+// header is a decoded initial byte with its argument.
+type header struct {
+	kind kind
+	b    bool
+	// arg is the value of integers, the length of strings, arrays and maps, and the tag number.
+	arg        uint64
+	f          float64
+	indefinite bool
+}
+
+type decoder struct {
+	data []byte
+	off  int
+}
+
+func (d *decoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, errShortData
+	}
+	b := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+func (d *decoder) header() (h header, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return h, err
+	}
+	major, ai := b[0]>>5, b[0]&0x1f
+
+	if major == majorSimple {
+		return d.simple(ai)
+	}
+
+	switch {
+	case ai < 24:
+		h.arg = uint64(ai)
+	case ai <= 27:
+		b, err := d.read(1 << (ai - 24))
+		if err != nil {
+			return h, err
+		}
+		switch len(b) {
+		case 1:
+			h.arg = uint64(b[0])
+		case 2:
+			h.arg = uint64(binary.BigEndian.Uint16(b))
+		case 4:
+			h.arg = uint64(binary.BigEndian.Uint32(b))
+		default:
+			h.arg = binary.BigEndian.Uint64(b)
+		}
+	case ai == 31 && major >= majorBytes && major <= majorMap:
+		h.indefinite = true
+	default:
+		return h, fmt.Errorf("cbor: invalid additional information %d for major type %d", ai, major)
+	}
+
+	h.kind = [...]kind{kindUint, kindNegInt, kindBytes, kindText, kindArray, kindMap, kindTag}[major]
+	if !h.indefinite {
+		remaining := uint64(len(d.data) - d.off)
+		switch h.kind {
+		case kindBytes, kindText, kindArray:
+			if h.arg > remaining {
+				return h, errShortData
+			}
+		case kindMap:
+			if h.arg > remaining/2 {
+				return h, errShortData
+			}
+		}
+	}
+	return h, nil
+}
+
+func (d *decoder) simple(ai byte) (h header, err error) {
+	switch ai {
+	case 20, 21:
+		return header{kind: kindBool, b: ai == 21}, nil
+	case 22, 23: // null, undefined
+		return header{kind: kindNil}, nil
+	case 25:
+		b, err := d.read(2)
+		if err != nil {
+			return h, err
+		}
+		return header{kind: kindFloat, f: float16(binary.BigEndian.Uint16(b))}, nil
+	case 26:
+		b, err := d.read(4)
+		if err != nil {
+			return h, err
+		}
+		return header{kind: kindFloat, f: float64(math.Float32frombits(binary.BigEndian.Uint32(b)))}, nil
+	case 27:
+		b, err := d.read(8)
+		if err != nil {
+			return h, err
+		}
+		return header{kind: kindFloat, f: math.Float64frombits(binary.BigEndian.Uint64(b))}, nil
+	case 31:
+		return header{kind: kindBreak}, nil
+	}
+	return h, fmt.Errorf("cbor: unsupported simple value %d", ai)
+}
+
+// float16 converts an IEEE 754 half-precision float.
+func float16(bits uint16) float64 {
+	exp := int(bits>>10) & 0x1f
+	mant := float64(bits & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if bits&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// atBreak consumes the break code ending an indefinite-length item, if it is next.
+func (d *decoder) atBreak() (bool, error) {
+	if d.off >= len(d.data) {
+		return false, errShortData
+	}
+	if d.data[d.off] == 0xff {
+		d.off++
+		return true, nil
+	}
+	return false, nil
+}
+
+// bytes returns the content of a byte or text string, joining the chunks of indefinite ones.
+func (d *decoder) bytes(h header) ([]byte, error) {
+	if !h.indefinite {
+		b, err := d.read(h.arg)
+		if err != nil {
+			return nil, err
+		}
+		if h.kind == kindText && !utf8.Valid(b) {
+			return nil, errors.New("cbor: invalid UTF-8 in text string")
+		}
+		return b, nil
+	}
+
+	var out []byte
+	for {
+		done, err := d.atBreak()
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return out, nil
+		}
+		chunk, err := d.header()
+		if err != nil {
+			return nil, err
+		}
+		if chunk.kind != h.kind || chunk.indefinite {
+			return nil, fmt.Errorf("cbor: invalid chunk in indefinite-length %s", h.kind)
+		}
+		b, err := d.bytes(chunk)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+	}
+}
+
+// items calls fn for every element of an array, or pair of a map, of h.
+func (d *decoder) items(h header, fn func() error) error {
+	for i := uint64(0); h.indefinite || i < h.arg; i++ {
+		if h.indefinite {
+			done, err := d.atBreak()
+			if err != nil {
+				return err
+			}
+			if done {
+				return nil
+			}
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) decode(v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return errTooDeep
+	}
+	h, err := d.header()
+	if err != nil {
+		return err
+	}
+	return d.decodeHeader(h, v, depth)
+}
+
+func typeError(h header, v reflect.Value) error {
+	return fmt.Errorf("cbor: cannot decode %s into Go value of type %s", h.kind, v.Type())
+}
+
+func (d *decoder) decodeHeader(h header, v reflect.Value, depth int) error {
+	switch h.kind {
+	case kindNil:
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	case kindBreak:
+		return errors.New("cbor: unexpected break")
+	}
+
+	switch {
+	case v.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeHeader(h, v.Elem(), depth+1)
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+		val, err := d.anyFrom(h, depth)
+		if err != nil {
+			return err
+		}
+		if val == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(val))
+		}
+		return nil
+	case v.Type() == timeType:
+		t, err := d.time(h, depth)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch h.kind {
+	case kindBool:
+		if v.Kind() != reflect.Bool {
+			return typeError(h, v)
+		}
+		v.SetBool(h.b)
+	case kindUint, kindNegInt, kindFloat:
+		return setNumber(h, v)
+	case kindBytes, kindText:
+		b, err := d.bytes(h)
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(b))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte(nil), b...))
+		case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+			reflect.Copy(v, reflect.ValueOf(b))
+		default:
+			return typeError(h, v)
+		}
+	case kindArray:
+		return d.decodeArray(h, v, depth)
+	case kindMap:
+		switch v.Kind() {
+		case reflect.Map:
+			return d.decodeMap(h, v, depth)
+		case reflect.Struct:
+			return d.decodeStruct(h, v, depth)
+		}
+		return typeError(h, v)
+	case kindTag:
+		// Unknown tags are transparent.
+		return d.decode(v, depth+1)
+	}
+	return nil
+}
+
+// setNumber stores an integer or float into a numeric value, checking for overflow.
+func setNumber(h header, v reflect.Value) error {
+	overflow := fmt.Errorf("cbor: %s overflows Go value of type %s", h.kind, v.Type())
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if h.kind == kindFloat {
+			return typeError(h, v)
+		}
+		if h.arg > math.MaxInt64 {
+			return overflow
+		}
+		n := int64(h.arg)
+		if h.kind == kindNegInt {
+			n = -1 - n
+		}
+		if v.OverflowInt(n) {
+			return overflow
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if h.kind == kindFloat {
+			return typeError(h, v)
+		}
+		if h.kind == kindNegInt || v.OverflowUint(h.arg) {
+			return overflow
+		}
+		v.SetUint(h.arg)
+	case reflect.Float32, reflect.Float64:
+		switch h.kind {
+		case kindUint:
+			v.SetFloat(float64(h.arg))
+		case kindNegInt:
+			v.SetFloat(-1 - float64(h.arg))
+		default:
+			v.SetFloat(h.f)
+		}
+	default:
+		return typeError(h, v)
+	}
+	return nil
+}
+
+func (d *decoder) decodeArray(h header, v reflect.Value, depth int) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 0, int(h.arg))
+		err := d.items(h, func() error {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(elem, depth+1); err != nil {
+				return err
+			}
+			s = reflect.Append(s, elem)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		v.Set(s)
+	case reflect.Array:
+		i := 0
+		err := d.items(h, func() error {
+			defer func() { i++ }()
+			if i < v.Len() {
+				return d.decode(v.Index(i), depth+1)
+			}
+			return d.skip(depth)
+		})
+		if err != nil {
+			return err
+		}
+		for ; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+	default:
+		return typeError(h, v)
+	}
+	return nil
+}
+
+func (d *decoder) decodeMap(h header, v reflect.Value, depth int) error {
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), int(h.arg)))
+	}
+	return d.items(h, func() error {
+		key := reflect.New(v.Type().Key()).Elem()
+		if err := d.decode(key, depth+1); err != nil {
+			return err
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := d.decode(elem, depth+1); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	})
+}
+
+// decodeStruct matches map keys to fields, unknown keys are skipped.
+func (d *decoder) decodeStruct(h header, v reflect.Value, depth int) error {
+	fields := structfields.Of(v.Type(), "cbor")
+	return d.items(h, func() error {
+		var name string
+		if err := d.decode(reflect.ValueOf(&name).Elem(), depth+1); err != nil {
+			return err
+		}
+		f, ok := structfields.ByName(fields, name)
+		if !ok {
+			return d.skip(depth)
+		}
+		fv, ok := structfields.FieldByIndex(v, f.Index, true)
+		if !ok {
+			return d.skip(depth)
+		}
+		return d.decode(fv, depth+1)
+	})
+}
+
+func (d *decoder) skip(depth int) error {
+	var discard any
+	return d.decode(reflect.ValueOf(&discard).Elem(), depth+1)
+}
+
+// anyFrom decodes the value of h into its natural Go type.
+func (d *decoder) anyFrom(h header, depth int) (any, error) {
+	switch h.kind {
+	case kindNil:
+		return nil, nil
+	case kindBool:
+		return h.b, nil
+	case kindUint:
+		if h.arg <= math.MaxInt64 {
+			return int64(h.arg), nil
+		}
+		return h.arg, nil
+	case kindNegInt:
+		if h.arg > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer overflows int64")
+		}
+		return -1 - int64(h.arg), nil
+	case kindFloat:
+		return h.f, nil
+	case kindText:
+		b, err := d.bytes(h)
+		return string(b), err
+	case kindBytes:
+		b, err := d.bytes(h)
+		return append([]byte(nil), b...), err
+	case kindArray:
+		s := make([]any, 0, int(h.arg))
+		err := d.items(h, func() error {
+			var elem any
+			if err := d.decode(reflect.ValueOf(&elem).Elem(), depth+1); err != nil {
+				return err
+			}
+			s = append(s, elem)
+			return nil
+		})
+		return s, err
+	case kindMap:
+		return d.anyMap(h, depth)
+	case kindTag:
+		if h.arg == tagDateTime || h.arg == tagEpoch {
+			return d.time(h, depth)
+		}
+		var val any
+		err := d.decode(reflect.ValueOf(&val).Elem(), depth+1)
+		return val, err
+	}
+	return nil, errors.New("cbor: unexpected break")
+}
+
+func (d *decoder) anyMap(h header, depth int) (any, error) {
+	m := make(map[string]any, int(h.arg))
+	var generic map[any]any
+	err := d.items(h, func() error {
+		var key, val any
+		if err := d.decode(reflect.ValueOf(&key).Elem(), depth+1); err != nil {
+			return err
+		}
+		if err := d.decode(reflect.ValueOf(&val).Elem(), depth+1); err != nil {
+			return err
+		}
+		if s, ok := key.(string); ok && generic == nil {
+			m[s] = val
+			return nil
+		}
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			return fmt.Errorf("cbor: unhashable map key of type %T", key)
+		}
+		if generic == nil {
+			generic = make(map[any]any, len(m)+1)
+			for k, v := range m {
+				generic[k] = v
+			}
+		}
+		generic[key] = val
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if generic != nil {
+		return generic, nil
+	}
+	return m, nil
+}
+
+// time decodes a time from tag 0 (RFC 3339 text) or tag 1 (epoch seconds).
+func (d *decoder) time(h header, depth int) (time.Time, error) {
+	if h.kind != kindTag || (h.arg != tagDateTime && h.arg != tagEpoch) {
+		return time.Time{}, fmt.Errorf("cbor: cannot decode %s into time.Time", h.kind)
+	}
+	if depth > maxDepth {
+		return time.Time{}, errTooDeep
+	}
+	content, err := d.header()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if h.arg == tagDateTime {
+		if content.kind != kindText {
+			return time.Time{}, errors.New("cbor: tag 0 content must be a text string")
+		}
+		b, err := d.bytes(content)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Parse(time.RFC3339Nano, string(b))
+	}
+
+	switch content.kind {
+	case kindUint:
+		if content.arg > math.MaxInt64 {
+			return time.Time{}, errors.New("cbor: epoch time overflows")
+		}
+		return time.Unix(int64(content.arg), 0), nil
+	case kindNegInt:
+		if content.arg > math.MaxInt64 {
+			return time.Time{}, errors.New("cbor: epoch time overflows")
+		}
+		return time.Unix(-1-int64(content.arg), 0), nil
+	case kindFloat:
+		if math.IsNaN(content.f) || math.IsInf(content.f, 0) {
+			return time.Time{}, errors.New("cbor: invalid epoch time")
+		}
+		sec, frac := math.Modf(content.f)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e9))), nil
+	}
+	return time.Time{}, errors.New("cbor: tag 1 content must be a number")
+}
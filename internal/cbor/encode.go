@@ -0,0 +1,214 @@
+// Package cbor implements the Concise Binary Object Representation of RFC 8949 for the
+// cbor binding and render. Struct fields are named by their cbor tag, then their json tag.
+// time.Time is encoded with tag 1 (epoch-based) and decoded from tag 0 or tag 1.
+package cbor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/crazyfrankie/gem/internal/structfields"
+)
+
+// maxDepth bounds the nesting of encoded and decoded values.
+const maxDepth = 1000
+
+// Major types.
+const (
+	majorUint byte = iota
+	majorNegInt
+	majorBytes
+	majorText
+	majorArray
+	majorMap
+	majorTag
+	majorSimple
+)
+
+const (
+	tagDateTime = 0
+	tagEpoch    = 1
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	errTooDeep = errors.New("cbor: exceeded max depth")
+)
+
+// Marshal returns the CBOR encoding of v.
+func Marshal(v any) ([]byte, error) {
+	var e encoder
+	if err := e.encode(reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+// head writes the initial byte of an item of major type major with argument n in its shortest form.
+func (e *encoder) head(major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		e.buf = append(e.buf, major|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, major|26), uint32(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, major|27), n)
+	}
+}
+
+func (e *encoder) null() {
+	e.buf = append(e.buf, 0xf6)
+}
+
+func (e *encoder) encode(v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return errTooDeep
+	}
+	if !v.IsValid() {
+		e.null()
+		return nil
+	}
+	if v.Type() == timeType {
+		e.time(v.Interface().(time.Time))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xf5)
+		} else {
+			e.buf = append(e.buf, 0xf4)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := v.Int(); n >= 0 {
+			e.head(majorUint, uint64(n))
+		} else {
+			e.head(majorNegInt, uint64(-1-n))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.head(majorUint, v.Uint())
+	case reflect.Float32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xfa), math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xfb), math.Float64bits(v.Float()))
+	case reflect.String:
+		e.head(majorText, uint64(v.Len()))
+		e.buf = append(e.buf, v.String()...)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.null()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.head(majorBytes, uint64(v.Len()))
+			e.buf = append(e.buf, v.Bytes()...)
+			return nil
+		}
+		return e.array(v, depth)
+	case reflect.Array:
+		return e.array(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			e.null()
+			return nil
+		}
+		return e.mapValue(v, depth)
+	case reflect.Struct:
+		return e.structValue(v, depth)
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.null()
+			return nil
+		}
+		return e.encode(v.Elem(), depth+1)
+	default:
+		return fmt.Errorf("cbor: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func (e *encoder) array(v reflect.Value, depth int) error {
+	e.head(majorArray, uint64(v.Len()))
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapValue encodes a map, string keys are sorted so the output is deterministic.
+func (e *encoder) mapValue(v reflect.Value, depth int) error {
+	keys := v.MapKeys()
+	if v.Type().Key().Kind() == reflect.String {
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	}
+	e.head(majorMap, uint64(len(keys)))
+	for _, k := range keys {
+		if err := e.encode(k, depth+1); err != nil {
+			return err
+		}
+		if err := e.encode(v.MapIndex(k), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) structValue(v reflect.Value, depth int) error {
+	fields := structfields.Of(v.Type(), "cbor")
+	values := make([]reflect.Value, len(fields))
+	n := 0
+	for i, f := range fields {
+		fv, ok := structfields.FieldByIndex(v, f.Index, false)
+		if !ok || (f.OmitEmpty && structfields.IsEmpty(fv)) {
+			continue
+		}
+		values[i] = fv
+		n++
+	}
+
+	e.head(majorMap, uint64(n))
+	for i, f := range fields {
+		if !values[i].IsValid() {
+			continue
+		}
+		e.head(majorText, uint64(len(f.Name)))
+		e.buf = append(e.buf, f.Name...)
+		if err := e.encode(values[i], depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// time writes t as tag 1, an integer when it has no fractional seconds and a float otherwise.
+func (e *encoder) time(t time.Time) {
+	e.head(majorTag, tagEpoch)
+	if t.Nanosecond() == 0 {
+		if sec := t.Unix(); sec >= 0 {
+			e.head(majorUint, uint64(sec))
+		} else {
+			e.head(majorNegInt, uint64(-1-sec))
+		}
+		return
+	}
+	f := float64(t.Unix()) + float64(t.Nanosecond())/1e9
+	e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xfb), math.Float64bits(f))
+}
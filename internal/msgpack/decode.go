@@ -0,0 +1,515 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/crazyfrankie/gem/internal/structfields"
+)
+
+var errShortData = errors.New("msgpack: unexpected end of data")
+
+// Unmarshal decodes the MessagePack data into v, which must be a non-nil pointer.
+// Into an interface value, integers decode as int64 (uint64 beyond its range), floats as
+// float64, maps as map[string]any, or map[any]any when a key is not a string.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("msgpack: Unmarshal(non-pointer %T)", v)
+	}
+	d := decoder{data: data}
+	if err := d.decode(rv.Elem(), 0); err != nil {
+		return err
+	}
+	if d.off != len(d.data) {
+		return errors.New("msgpack: trailing data after the top-level value")
+	}
+	return nil
+}
+
+type kind uint8
+
+const (
+	kindNil kind = iota
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindStr
+	kindBin
+	kindArray
+	kindMap
+	kindExt
+)
+
+var kindNames = [...]string{"nil", "bool", "int", "uint", "float", "str", "bin", "array", "map", "ext"}
+
+func (k kind) String() string {
+	return kindNames[k]
+}
+
+// header is a decoded type marker with its value or length.
+type header struct {
+	kind kind
+	b    bool
+	i    int64
+	u    uint64
+	f    float64
+	// n is the length of str, bin and ext data, and the number of array elements or map pairs.
+	n       int
+	extType int8
+}
+
+type decoder struct {
+	data []byte
+	off  int
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.off < n {
+		return nil, errShortData
+	}
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+func (d *decoder) uintN(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// length reads a length of size bytes, rejecting lengths the remaining data can not hold
+// given that every item takes at least perItem bytes.
+func (d *decoder) length(size, perItem int) (int, error) {
+	n, err := d.uintN(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.data)-d.off)/uint64(perItem) {
+		return 0, errShortData
+	}
+	return int(n), nil
+}
+
+func (d *decoder) header() (h header, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return h, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return header{kind: kindUint, u: uint64(c)}, nil
+	case c >= 0xe0:
+		return header{kind: kindInt, i: int64(int8(c))}, nil
+	case c&0xf0 == 0x80:
+		h = header{kind: kindMap, n: int(c & 0x0f)}
+	case c&0xf0 == 0x90:
+		h = header{kind: kindArray, n: int(c & 0x0f)}
+	case c&0xe0 == 0xa0:
+		h = header{kind: kindStr, n: int(c & 0x1f)}
+	default:
+		return d.longHeader(c)
+	}
+	perItem := 1
+	if h.kind == kindMap {
+		perItem = 2
+	}
+	if h.n > (len(d.data)-d.off)/perItem {
+		return h, errShortData
+	}
+	return h, nil
+}
+
+func (d *decoder) longHeader(c byte) (h header, err error) {
+	switch c {
+	case 0xc0:
+		return header{kind: kindNil}, nil
+	case 0xc2, 0xc3:
+		return header{kind: kindBool, b: c == 0xc3}, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		h.kind = kindUint
+		h.u, err = d.uintN(1 << (c - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		var u uint64
+		size := 1 << (c - 0xd0)
+		u, err = d.uintN(size)
+		h.kind = kindInt
+		switch size {
+		case 1:
+			h.i = int64(int8(u))
+		case 2:
+			h.i = int64(int16(u))
+		case 4:
+			h.i = int64(int32(u))
+		default:
+			h.i = int64(u)
+		}
+	case 0xca:
+		var u uint64
+		u, err = d.uintN(4)
+		h = header{kind: kindFloat, f: float64(math.Float32frombits(uint32(u)))}
+	case 0xcb:
+		var u uint64
+		u, err = d.uintN(8)
+		h = header{kind: kindFloat, f: math.Float64frombits(u)}
+	case 0xd9, 0xda, 0xdb:
+		h.kind = kindStr
+		h.n, err = d.length(1<<(c-0xd9), 1)
+	case 0xc4, 0xc5, 0xc6:
+		h.kind = kindBin
+		h.n, err = d.length(1<<(c-0xc4), 1)
+	case 0xdc, 0xdd:
+		h.kind = kindArray
+		h.n, err = d.length(2<<(c-0xdc), 1)
+	case 0xde, 0xdf:
+		h.kind = kindMap
+		h.n, err = d.length(2<<(c-0xde), 2)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		h = header{kind: kindExt, n: 1 << (c - 0xd4)}
+		err = d.extType(&h)
+	case 0xc7, 0xc8, 0xc9:
+		h.kind = kindExt
+		if h.n, err = d.length(1<<(c-0xc7), 1); err == nil {
+			err = d.extType(&h)
+		}
+	default:
+		return h, fmt.Errorf("msgpack: invalid type marker 0x%02x", c)
+	}
+	return h, err
+}
+
+func (d *decoder) extType(h *header) error {
+	b, err := d.read(1)
+	if err != nil {
+		return err
+	}
+	h.extType = int8(b[0])
+	return nil
+}
+
+func (d *decoder) decode(v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return errTooDeep
+	}
+	h, err := d.header()
+	if err != nil {
+		return err
+	}
+	return d.decodeHeader(h, v, depth)
+}
+
+func (d *decoder) typeError(h header, v reflect.Value) error {
+	return fmt.Errorf("msgpack: cannot decode %s into Go value of type %s", h.kind, v.Type())
+}
+
+func (d *decoder) decodeHeader(h header, v reflect.Value, depth int) error {
+	if h.kind == kindNil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch {
+	case v.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeHeader(h, v.Elem(), depth+1)
+	case v.Kind() == reflect.Interface && v.NumMethod() == 0:
+		val, err := d.anyFrom(h, depth)
+		if err != nil {
+			return err
+		}
+		if val == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(val))
+		}
+		return nil
+	case v.Type() == timeType:
+		if h.kind != kindExt || h.extType != timestampExt {
+			return d.typeError(h, v)
+		}
+		t, err := d.time(h)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch h.kind {
+	case kindBool:
+		if v.Kind() != reflect.Bool {
+			return d.typeError(h, v)
+		}
+		v.SetBool(h.b)
+	case kindInt, kindUint, kindFloat:
+		return setNumber(h, v)
+	case kindStr, kindBin:
+		b, err := d.read(h.n)
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(string(b))
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes(append([]byte(nil), b...))
+		case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+			reflect.Copy(v, reflect.ValueOf(b))
+		default:
+			return d.typeError(h, v)
+		}
+	case kindArray:
+		return d.decodeArray(h, v, depth)
+	case kindMap:
+		switch v.Kind() {
+		case reflect.Map:
+			return d.decodeMap(h, v, depth)
+		case reflect.Struct:
+			return d.decodeStruct(h, v, depth)
+		}
+		return d.typeError(h, v)
+	default:
+		return d.typeError(h, v)
+	}
+	return nil
+}
+
+// setNumber stores an int, uint or float into a numeric value, checking for overflow.
+func setNumber(h header, v reflect.Value) error {
+	overflow := fmt.Errorf("msgpack: %s value overflows Go value of type %s", h.kind, v.Type())
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch h.kind {
+		case kindInt:
+			n = h.i
+		case kindUint:
+			if h.u > math.MaxInt64 {
+				return overflow
+			}
+			n = int64(h.u)
+		default:
+			return fmt.Errorf("msgpack: cannot decode float into Go value of type %s", v.Type())
+		}
+		if v.OverflowInt(n) {
+			return overflow
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch h.kind {
+		case kindUint:
+			n = h.u
+		case kindInt:
+			if h.i < 0 {
+				return overflow
+			}
+			n = uint64(h.i)
+		default:
+			return fmt.Errorf("msgpack: cannot decode float into Go value of type %s", v.Type())
+		}
+		if v.OverflowUint(n) {
+			return overflow
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		switch h.kind {
+		case kindInt:
+			v.SetFloat(float64(h.i))
+		case kindUint:
+			v.SetFloat(float64(h.u))
+		default:
+			v.SetFloat(h.f)
+		}
+	default:
+		return fmt.Errorf("msgpack: cannot decode %s into Go value of type %s", h.kind, v.Type())
+	}
+	return nil
+}
+
+func (d *decoder) decodeArray(h header, v reflect.Value, depth int) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), h.n, h.n)
+		for i := 0; i < h.n; i++ {
+			if err := d.decode(s.Index(i), depth+1); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < h.n; i++ {
+			if i < v.Len() {
+				if err := d.decode(v.Index(i), depth+1); err != nil {
+					return err
+				}
+			} else if err := d.skip(depth); err != nil {
+				return err
+			}
+		}
+		for i := h.n; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+	default:
+		return d.typeError(h, v)
+	}
+	return nil
+}
+
+func (d *decoder) decodeMap(h header, v reflect.Value, depth int) error {
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), h.n))
+	}
+	for i := 0; i < h.n; i++ {
+		key := reflect.New(v.Type().Key()).Elem()
+		if err := d.decode(key, depth+1); err != nil {
+			return err
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := d.decode(elem, depth+1); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+// decodeStruct matches map keys to fields, unknown keys are skipped.
+func (d *decoder) decodeStruct(h header, v reflect.Value, depth int) error {
+	fields := structfields.Of(v.Type(), "msgpack")
+	for i := 0; i < h.n; i++ {
+		var name string
+		if err := d.decode(reflect.ValueOf(&name).Elem(), depth+1); err != nil {
+			return err
+		}
+		f, ok := structfields.ByName(fields, name)
+		if !ok {
+			if err := d.skip(depth); err != nil {
+				return err
+			}
+			continue
+		}
+		fv, ok := structfields.FieldByIndex(v, f.Index, true)
+		if !ok {
+			if err := d.skip(depth); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.decode(fv, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) skip(depth int) error {
+	var discard any
+	return d.decode(reflect.ValueOf(&discard).Elem(), depth+1)
+}
+
+// anyFrom decodes the value of h into its natural Go type.
+func (d *decoder) anyFrom(h header, depth int) (any, error) {
+	switch h.kind {
+	case kindNil:
+		return nil, nil
+	case kindBool:
+		return h.b, nil
+	case kindInt:
+		return h.i, nil
+	case kindUint:
+		if h.u <= math.MaxInt64 {
+			return int64(h.u), nil
+		}
+		return h.u, nil
+	case kindFloat:
+		return h.f, nil
+	case kindStr:
+		b, err := d.read(h.n)
+		return string(b), err
+	case kindBin:
+		b, err := d.read(h.n)
+		return append([]byte(nil), b...), err
+	case kindArray:
+		s := make([]any, h.n)
+		for i := range s {
+			if err := d.decode(reflect.ValueOf(&s[i]).Elem(), depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	case kindMap:
+		return d.anyMap(h, depth)
+	}
+	if h.extType != timestampExt {
+		return nil, fmt.Errorf("msgpack: unsupported extension type %d", h.extType)
+	}
+	return d.time(h)
+}
+
+func (d *decoder) anyMap(h header, depth int) (any, error) {
+	m := make(map[string]any, h.n)
+	var generic map[any]any
+	for i := 0; i < h.n; i++ {
+		var key, val any
+		if err := d.decode(reflect.ValueOf(&key).Elem(), depth+1); err != nil {
+			return nil, err
+		}
+		if err := d.decode(reflect.ValueOf(&val).Elem(), depth+1); err != nil {
+			return nil, err
+		}
+		if s, ok := key.(string); ok && generic == nil {
+			m[s] = val
+			continue
+		}
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			return nil, fmt.Errorf("msgpack: unhashable map key of type %T", key)
+		}
+		if generic == nil {
+			generic = make(map[any]any, h.n)
+			for k, v := range m {
+				generic[k] = v
+			}
+		}
+		generic[key] = val
+	}
+	if generic != nil {
+		return generic, nil
+	}
+	return m, nil
+}
+
+func (d *decoder) time(h header) (time.Time, error) {
+	b, err := d.read(h.n)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch h.n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0), nil
+	case 8:
+		data := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(data&0x3ffffffff), int64(data>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b))), nil
+	}
+	return time.Time{}, fmt.Errorf("msgpack: invalid timestamp length %d", h.n)
+}
@@ -0,0 +1,255 @@
+// Package msgpack implements the MessagePack format (https://msgpack.org/) for the
+// msgpack binding and render. Struct fields are named by their msgpack tag,
+// then their json tag, and time.Time uses the timestamp extension type -1.
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/crazyfrankie/gem/internal/structfields"
+)
+
+// maxDepth bounds the nesting of encoded and decoded values.
+const maxDepth = 1000
+
+const timestampExt = -1
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	errTooDeep = errors.New("msgpack: exceeded max depth")
+)
+
+// Marshal returns the MessagePack encoding of v.
+func Marshal(v any) ([]byte, error) {
+	var e encoder
+	if err := e.encode(reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) encode(v reflect.Value, depth int) error {
+	if depth > maxDepth {
+		return errTooDeep
+	}
+	if !v.IsValid() {
+		e.buf = append(e.buf, 0xc0)
+		return nil
+	}
+	if v.Type() == timeType {
+		e.time(v.Interface().(time.Time))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.int(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.uint(v.Uint())
+	case reflect.Float32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xca), math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcb), math.Float64bits(v.Float()))
+	case reflect.String:
+		e.str(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.bin(v.Bytes())
+			return nil
+		}
+		return e.array(v, depth)
+	case reflect.Array:
+		return e.array(v, depth)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		return e.mapValue(v, depth)
+	case reflect.Struct:
+		return e.structValue(v, depth)
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		return e.encode(v.Elem(), depth+1)
+	default:
+		return fmt.Errorf("msgpack: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func (e *encoder) int(n int64) {
+	switch {
+	case n >= 0:
+		e.uint(uint64(n))
+	case n >= -32:
+		e.buf = append(e.buf, byte(int8(n)))
+	case n >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(int8(n)))
+	case n >= math.MinInt16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xd1), uint16(int16(n)))
+	case n >= math.MinInt32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xd2), uint32(int32(n)))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xd3), uint64(n))
+	}
+}
+
+func (e *encoder) uint(n uint64) {
+	switch {
+	case n <= 0x7f:
+		e.buf = append(e.buf, byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xce), uint32(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcf), n)
+	}
+}
+
+func (e *encoder) str(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xda), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdb), uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) bin(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xc5), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xc6), uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) arrayHeader(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xdc), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdd), uint32(n))
+	}
+}
+
+func (e *encoder) mapHeader(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xde), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdf), uint32(n))
+	}
+}
+
+func (e *encoder) array(v reflect.Value, depth int) error {
+	e.arrayHeader(v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapValue encodes a map, string keys are sorted so the output is deterministic.
+func (e *encoder) mapValue(v reflect.Value, depth int) error {
+	keys := v.MapKeys()
+	if v.Type().Key().Kind() == reflect.String {
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	}
+	e.mapHeader(len(keys))
+	for _, k := range keys {
+		if err := e.encode(k, depth+1); err != nil {
+			return err
+		}
+		if err := e.encode(v.MapIndex(k), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *encoder) structValue(v reflect.Value, depth int) error {
+	fields := structfields.Of(v.Type(), "msgpack")
+	values := make([]reflect.Value, len(fields))
+	n := 0
+	for i, f := range fields {
+		fv, ok := structfields.FieldByIndex(v, f.Index, false)
+		if !ok || (f.OmitEmpty && structfields.IsEmpty(fv)) {
+			continue
+		}
+		values[i] = fv
+		n++
+	}
+
+	e.mapHeader(n)
+	for i, f := range fields {
+		if !values[i].IsValid() {
+			continue
+		}
+		e.str(f.Name)
+		if err := e.encode(values[i], depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// time writes t with the timestamp extension in its smallest form.
+func (e *encoder) time(t time.Time) {
+	sec, nsec := uint64(t.Unix()), uint64(t.Nanosecond())
+	if sec>>34 == 0 {
+		data := nsec<<34 | sec
+		if data&0xffffffff00000000 == 0 {
+			e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xd6, byte(0xff)), uint32(data))
+			return
+		}
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xd7, byte(0xff)), data)
+		return
+	}
+	e.buf = append(e.buf, 0xc7, 12, byte(0xff))
+	e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(nsec))
+	e.buf = binary.BigEndian.AppendUint64(e.buf, sec)
+}
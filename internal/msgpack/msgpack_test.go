@@ -0,0 +1,157 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeVectors(t *testing.T) {
+	tests := []struct {
+		v   any
+		hex string
+	}{
+		{nil, "c0"},
+		{false, "c2"},
+		{true, "c3"},
+		{0, "00"},
+		{127, "7f"},
+		{128, "cc80"},
+		{256, "cd0100"},
+		{70000, "ce00011170"},
+		{uint64(math.MaxUint64), "cfffffffffffffffff"},
+		{-1, "ff"},
+		{-32, "e0"},
+		{-33, "d0df"},
+		{-129, "d1ff7f"},
+		{-40000, "d2ffff63c0"},
+		{int64(math.MinInt64), "d38000000000000000"},
+		{float32(1.5), "ca3fc00000"},
+		{1.5, "cb3ff8000000000000"},
+		{"abc", "a3616263"},
+		{strings.Repeat("a", 32), "d920" + strings.Repeat("61", 32)},
+		{[]byte{1, 2}, "c4020102"},
+		{[]int{1, 2}, "920102"},
+		{map[string]int{"b": 2, "a": 1}, "82a16101a16202"},
+		{time.Unix(1, 0), "d6ff00000001"},
+		{time.Unix(1, 5), "d7ff0000001400000001"},
+		{time.Unix(1<<35, 1), "c70cff000000010000000800000000"},
+	}
+	for _, tt := range tests {
+		got, err := Marshal(tt.v)
+		if err != nil || hex.EncodeToString(got) != tt.hex {
+			t.Errorf("%v: got %x, %v, want %s", tt.v, got, err, tt.hex)
+		}
+	}
+}
+
+func TestDecodeAny(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want any
+	}{
+		{"c0", nil},
+		{"c3", true},
+		{"7f", int64(127)},
+		{"e0", int64(-32)},
+		{"cfffffffffffffffff", uint64(math.MaxUint64)},
+		{"d1ff7f", int64(-129)},
+		{"ca3fc00000", 1.5},
+		{"a3616263", "abc"},
+		{"c4020102", []byte{1, 2}},
+		{"920102", []any{int64(1), int64(2)}},
+		{"82a16101a16202", map[string]any{"a": int64(1), "b": int64(2)}},
+		{"810102", map[any]any{int64(1): int64(2)}},
+	}
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.hex)
+		var got any
+		if err := Unmarshal(data, &got); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, %v, want %#v", tt.hex, got, err, tt.want)
+		}
+	}
+}
+
+type Embedded struct {
+	Level int `msgpack:"level"`
+}
+
+type record struct {
+	Embedded
+	Name    string            `msgpack:"name"`
+	Alias   string            `json:"alias,omitempty"`
+	Scores  []float64         `msgpack:"scores"`
+	Meta    map[string]string `msgpack:"meta"`
+	When    time.Time         `msgpack:"when"`
+	Parent  *record           `msgpack:"parent"`
+	Raw     []byte            `msgpack:"raw"`
+	Ignored string            `msgpack:"-"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := record{
+		Embedded: Embedded{Level: -3},
+		Name:     strings.Repeat("long name ", 30),
+		Scores:   []float64{1.5, -2},
+		Meta:     map[string]string{"k": "v"},
+		When:     time.Unix(1700000000, 123456789),
+		Parent:   &record{Name: "root"},
+		Raw:      bytes.Repeat([]byte{1}, 300),
+		Ignored:  "x",
+	}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("alias")) || bytes.Contains(data, []byte("Ignored")) {
+		t.Fatal("omitempty or - field encoded")
+	}
+
+	var out record
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	in.Ignored = ""
+	if !out.When.Equal(in.When) {
+		t.Fatalf("time: got %v", out.When)
+	}
+	out.When, in.When = time.Time{}, time.Time{}
+	out.Parent.When, in.Parent.When = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("got  %+v\nwant %+v", out, in)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		hex string
+		v   any
+	}{
+		{"", new(any)},
+		{"cd01", new(any)},       // truncated
+		{"dbffffffff", new(any)}, // length beyond data
+		{"0001", new(any)},       // trailing data
+		{"cd0100", new(int8)},    // overflow
+		{"ff", new(uint)},        // negative into unsigned
+		{"a161", new(int)},       // type mismatch
+		{"c1", new(any)},         // never used marker
+		{"d401ff", new(any)},     // unknown extension
+	}
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.hex)
+		if err := Unmarshal(data, tt.v); err == nil {
+			t.Errorf("%s: expected error", tt.hex)
+		}
+	}
+
+	deep := bytes.Repeat([]byte{0x91}, maxDepth+10)
+	deep = append(deep, 0x00)
+	var v any
+	if err := Unmarshal(deep, &v); err == nil {
+		t.Error("expected max depth error")
+	}
+}
@@ -0,0 +1,180 @@
+// Package structfields resolves the serialized fields of struct types for the in-tree codecs,
+// following the rules of encoding/json: embedded structs are flattened and the shallowest
+// field wins a name conflict.
+package structfields
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field is a serialized struct field.
+type Field struct {
+	Name string
+	// Index is the index sequence for reflect.Value.FieldByIndex.
+	Index     []int
+	Type      reflect.Type
+	OmitEmpty bool
+}
+
+type cacheKey struct {
+	t   reflect.Type
+	tag string
+}
+
+var cache sync.Map
+
+// Of returns the fields of the struct type t. The name of a field comes from tag,
+// then from its json tag, then from the field name; "-" leaves the field out.
+func Of(t reflect.Type, tag string) []Field {
+	key := cacheKey{t: t, tag: tag}
+	if fields, ok := cache.Load(key); ok {
+		return fields.([]Field)
+	}
+	fields, _ := cache.LoadOrStore(key, resolve(t, tag))
+	return fields.([]Field)
+}
+
+// ByName returns the field named name, falling back to a case-insensitive match.
+func ByName(fields []Field, name string) (Field, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+func resolve(t reflect.Type, tag string) []Field {
+	var fields []Field
+	collect(t, tag, nil, map[reflect.Type]bool{}, &fields)
+
+	// Keep the shallowest field of every name, dropping ambiguous ones at the same depth.
+	sort.SliceStable(fields, func(i, j int) bool { return len(fields[i].Index) < len(fields[j].Index) })
+	depth := make(map[string]int, len(fields))
+	count := make(map[string]int, len(fields))
+	for _, f := range fields {
+		if d, ok := depth[f.Name]; !ok || d == len(f.Index) {
+			depth[f.Name] = len(f.Index)
+			count[f.Name]++
+		}
+	}
+	out := fields[:0]
+	for _, f := range fields {
+		if depth[f.Name] == len(f.Index) && count[f.Name] == 1 {
+			out = append(out, f)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return lessIndex(out[i].Index, out[j].Index) })
+	return out
+}
+
+func collect(t reflect.Type, tag string, index []int, visited map[reflect.Type]bool, fields *[]Field) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if !sf.IsExported() && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+			continue
+		}
+
+		name, opts := lookupTag(sf, tag)
+		if name == "-" && opts == "" {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			collect(ft, tag, fieldIndex, visited, fields)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		*fields = append(*fields, Field{
+			Name:      name,
+			Index:     fieldIndex,
+			Type:      sf.Type,
+			OmitEmpty: hasOption(opts, "omitempty"),
+		})
+	}
+}
+
+func lookupTag(sf reflect.StructField, tag string) (name, opts string) {
+	value, ok := sf.Tag.Lookup(tag)
+	if !ok {
+		value = sf.Tag.Get("json")
+	}
+	name, opts, _ = strings.Cut(value, ",")
+	return name, opts
+}
+
+func hasOption(opts, option string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+func lessIndex(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// FieldByIndex returns the field of v at index. Nil embedded pointers are allocated
+// when alloc is set, otherwise ok is false.
+func FieldByIndex(v reflect.Value, index []int, alloc bool) (field reflect.Value, ok bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// IsEmpty reports whether v is empty in the sense of the omitempty option of encoding/json.
+func IsEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
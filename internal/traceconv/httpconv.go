@@ -1,7 +1,7 @@
 package traceconv
 
 import (
-	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -40,11 +40,14 @@ func (c *HttpConv) HTTPScheme(https bool) attribute.KeyValue {
 	return attribute.Key("http.scheme").String("http")
 }
 
+// SplitHostPort splits hostport into host and port, the port is zero when absent or invalid.
 func (c *HttpConv) SplitHostPort(hostport string) (string, int) {
-	hosts := strings.Split(hostport, ":")
-	port, _ := strconv.Atoi(hosts[1])
-	fmt.Println(port)
-	return hosts[0], port
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport, 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return host, port
 }
 
 func (n *netConv) NetHostName(host string) attribute.KeyValue {
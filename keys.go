@@ -0,0 +1,89 @@
+package gem
+
+import (
+	"context"
+	"fmt"
+)
+
+// Key is a typed key for values stored on a Context.
+// Every key returned by NewKey is distinct, even when two keys share a name,
+// so values can not collide the way string keys in Context.Keys can.
+//
+//	var UserKey = gem.NewKey[*User]("user")
+//
+//	UserKey.Set(c, user)
+//	user, ok := UserKey.Get(c)
+//
+// Values are also visible through Context.Value, so code that only receives the
+// Context as a context.Context can read them with Key.From.
+type Key[T any] struct {
+	id *keyID
+}
+
+type keyID struct {
+	name string
+}
+
+// typedKey is implemented by every Key instantiation, it lets Context.Value recognize them.
+type typedKey interface {
+	gemKey()
+}
+
+// NewKey returns a new key for values of type T. name is only used for debugging.
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{id: &keyID{name: name}}
+}
+
+func (k Key[T]) gemKey() {}
+
+// String returns the name of the key.
+func (k Key[T]) String() string {
+	if k.id == nil {
+		return "gem.Key[<nil>]"
+	}
+	return "gem.Key[" + k.id.name + "]"
+}
+
+// Set stores v on the Context under k.
+func (k Key[T]) Set(c *Context, v T) {
+	assert(k.id != nil, "gem: Key must be created with NewKey")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.typedKeys == nil {
+		c.typedKeys = make(map[any]any)
+	}
+	c.typedKeys[k] = v
+}
+
+// Get returns the value stored on the Context under k.
+func (k Key[T]) Get(c *Context) (value T, exists bool) {
+	v, ok := c.getTyped(k)
+	if !ok || v == nil {
+		// A nil interface stored for an interface type T does not survive the assertion below.
+		return value, ok
+	}
+	value, exists = v.(T)
+	return
+}
+
+// MustGet returns the value stored on the Context under k, otherwise panic.
+func (k Key[T]) MustGet(c *Context) T {
+	if value, exists := k.Get(c); exists {
+		return value
+	}
+	panic(fmt.Sprintf("Key %s does not exist", k))
+}
+
+// From returns the value stored under k on the Context behind ctx.
+// It works with any context.Context derived from a *Context.
+func (k Key[T]) From(ctx context.Context) (value T, exists bool) {
+	value, exists = ctx.Value(k).(T)
+	return
+}
+
+func (c *Context) getTyped(key typedKey) (any, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.typedKeys[key]
+	return v, ok
+}
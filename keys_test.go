@@ -0,0 +1,103 @@
+package gem
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type otherCtxKey struct{}
+
+type keyUser struct {
+	Name string
+}
+
+func TestKey(t *testing.T) {
+	userKey := NewKey[*keyUser]("user")
+	otherUserKey := NewKey[*keyUser]("user")
+	countKey := NewKey[int]("count")
+	errKey := NewKey[error]("err")
+
+	c := &Context{}
+	if _, ok := userKey.Get(c); ok {
+		t.Error("Get on an empty Context found a value")
+	}
+
+	user := &keyUser{Name: "gem"}
+	userKey.Set(c, user)
+	countKey.Set(c, 3)
+	errKey.Set(c, nil)
+	if got, ok := userKey.Get(c); !ok || got != user {
+		t.Errorf("Get = %v, %v", got, ok)
+	}
+	if got := countKey.MustGet(c); got != 3 {
+		t.Errorf("MustGet = %d", got)
+	}
+	if got, ok := errKey.Get(c); !ok || got != nil {
+		t.Errorf("nil interface value: %v, %v", got, ok)
+	}
+	// Keys are distinct even with the same name and type, and never clash with string keys.
+	if _, ok := otherUserKey.Get(c); ok {
+		t.Error("a key with the same name read another key's value")
+	}
+	c.Set("user", "string value")
+	if got, _ := userKey.Get(c); got != user {
+		t.Errorf("string key overwrote the typed key: %v", got)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("MustGet of a missing key did not panic")
+			}
+		}()
+		otherUserKey.MustGet(c)
+	}()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Set with a zero Key did not panic")
+			}
+		}()
+		Key[int]{}.Set(c, 1)
+	}()
+
+	// The copy holds its own values.
+	cp := c.Copy()
+	countKey.Set(cp, 4)
+	if got := countKey.MustGet(c); got != 3 {
+		t.Errorf("setting a key on the copy changed the original: %d", got)
+	}
+	if got, ok := userKey.Get(cp); !ok || got != user {
+		t.Errorf("copy lost the typed key: %v, %v", got, ok)
+	}
+}
+
+func TestKeyFrom(t *testing.T) {
+	userKey := NewKey[*keyUser]("user")
+	countKey := NewKey[int]("count")
+	server := New()
+	server.GET("/", func(c *Context) {
+		userKey.Set(c, &keyUser{Name: "gem"})
+
+		// Code that only sees a context.Context, such as a database call.
+		var ctx context.Context = c
+		ctx = context.WithValue(ctx, otherCtxKey{}, 1)
+		if u, ok := userKey.From(ctx); !ok || u.Name != "gem" {
+			t.Errorf("From = %v, %v", u, ok)
+		}
+		if _, ok := countKey.From(ctx); ok {
+			t.Error("From found a value never set")
+		}
+		if v, ok := ctx.Value(userKey).(*keyUser); !ok || v.Name != "gem" {
+			t.Errorf("Value = %v", ctx.Value(userKey))
+		}
+		c.Status(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("handler did not run: %d", w.Code)
+	}
+}
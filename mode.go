@@ -0,0 +1,63 @@
+package gem
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// EnvGemMode indicates environment name for gem mode.
+const EnvGemMode = "GEM_MODE"
+
+const (
+	// DebugMode enables runtime checks that catch misuse of the framework, at some cost in speed.
+	// Contexts are not pooled in this mode: every request allocates its own, so that a Context
+	// used after its handler returned warns and reports the completed request rather than
+	// silently reading the request it was reused for.
+	DebugMode = "debug"
+	// ReleaseMode disables the debug checks.
+	ReleaseMode = "release"
+	// TestMode behaves like ReleaseMode, it is meant for the tests of applications.
+	TestMode = "test"
+)
+
+// DefaultWriter is the default io.Writer used by gem for debug output.
+var DefaultWriter io.Writer = os.Stdout
+
+var gemMode atomic.Value
+
+func init() {
+	SetMode(os.Getenv(EnvGemMode))
+}
+
+// SetMode sets gem mode according to input string.
+// An empty string selects ReleaseMode.
+func SetMode(value string) {
+	switch value {
+	case "":
+		value = ReleaseMode
+	case DebugMode, ReleaseMode, TestMode:
+	default:
+		panic("gem mode unknown: " + value + " (available mode: debug release test)")
+	}
+	gemMode.Store(value)
+}
+
+// Mode returns current gem mode.
+func Mode() string {
+	return gemMode.Load().(string)
+}
+
+// IsDebugging returns true if the framework is running in debug mode.
+// Use SetMode(gem.ReleaseMode) to disable debug mode.
+func IsDebugging() bool {
+	return Mode() == DebugMode
+}
+
+func debugPrint(format string, values ...any) {
+	if !IsDebugging() {
+		return
+	}
+	fmt.Fprintf(DefaultWriter, "[GEM-debug] "+format, values...)
+}
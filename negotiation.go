@@ -0,0 +1,186 @@
+package gem
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/crazyfrankie/gem/render"
+)
+
+// Negotiate contains all negotiation data.
+type Negotiate struct {
+	// Offered lists the media types the handler can produce, in order of preference.
+	Offered []string
+	// Data is passed to the render of the selected media type.
+	Data any
+}
+
+// NegotiateRenderFunc builds the render used when its media type wins the negotiation.
+type NegotiateRenderFunc func(data any) render.Render
+
+var negotiateRenders = struct {
+	sync.RWMutex
+	m map[string]NegotiateRenderFunc
+}{m: map[string]NegotiateRenderFunc{
+	MIMEJSON:     func(data any) render.Render { return render.JSON{Data: data} },
+	MIMEXML:      func(data any) render.Render { return render.XML{Data: data} },
+	MIMEXML2:     func(data any) render.Render { return render.XML{Data: data} },
+	MIMEYAML:     func(data any) render.Render { return render.YAML{Data: data} },
+	MIMEYAML2:    func(data any) render.Render { return render.YAML{Data: data} },
+	MIMEPROTOBUF: func(data any) render.Render { return render.ProtoBuf{Data: data} },
+	MIMEMSGPACK:  func(data any) render.Render { return render.MsgPack{Data: data} },
+	MIMEMSGPACK2: func(data any) render.Render { return render.MsgPack{Data: data} },
+	MIMECBOR:     func(data any) render.Render { return render.CBOR{Data: data} },
+	MIMEPlain:    func(data any) render.Render { return render.String{Format: "%v", Data: []any{data}} },
+}}
+
+// RegisterNegotiateRender makes the media type mime available to Context.Negotiate.
+// Registering an already known media type replaces its render.
+func RegisterNegotiateRender(mime string, fn NegotiateRenderFunc) {
+	assert(fn != nil, "negotiate render can not be nil")
+	negotiateRenders.Lock()
+	defer negotiateRenders.Unlock()
+	negotiateRenders.m[mediaType(mime)] = fn
+}
+
+func lookupNegotiateRender(mime string) NegotiateRenderFunc {
+	negotiateRenders.RLock()
+	defer negotiateRenders.RUnlock()
+	return negotiateRenders.m[mediaType(mime)]
+}
+
+// Negotiate calls different Render according to acceptable Accept format.
+// Only offered media types with a registered render take part,
+// it aborts with 406 Not Acceptable when none of them is acceptable.
+func (c *Context) Negotiate(code int, config Negotiate) {
+	addVary(c.Writer.Header(), "Accept")
+
+	offered := make([]string, 0, len(config.Offered))
+	for _, mime := range config.Offered {
+		if lookupNegotiateRender(mime) != nil {
+			offered = append(offered, mime)
+		}
+	}
+
+	// Offering only types without a registered render leaves nothing acceptable.
+	if len(offered) == 0 {
+		c.AbortWithStatus(http.StatusNotAcceptable)
+		return
+	}
+	format := c.NegotiateFormat(offered...)
+	if format == "" {
+		c.AbortWithStatus(http.StatusNotAcceptable)
+		return
+	}
+	c.Render(code, lookupNegotiateRender(format)(config.Data))
+}
+
+// NegotiateFormat returns the offered media type that best matches the Accept header,
+// honoring q-values and wildcards. Without an Accept header the first offered type is returned,
+// an empty string means none of them is acceptable.
+func (c *Context) NegotiateFormat(offered ...string) string {
+	assert(len(offered) > 0, "you must provide at least one offer")
+
+	accept := c.Request.Header.Values("Accept")
+	if len(accept) == 0 {
+		return offered[0]
+	}
+	ranges := parseAccept(strings.Join(accept, ","))
+	if len(ranges) == 0 {
+		return offered[0]
+	}
+
+	best, bestQ, bestSpec := "", 0.0, -1
+	for _, offer := range offered {
+		q, spec := acceptQuality(ranges, mediaType(offer))
+		if q > bestQ || (q == bestQ && q > 0 && spec > bestSpec) {
+			best, bestQ, bestSpec = offer, q, spec
+		}
+	}
+	return best
+}
+
+// acceptRange is a single media range of an Accept header.
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept parses an Accept header value, skipping malformed media ranges.
+func parseAccept(header string) []acceptRange {
+	parts := strings.Split(header, ",")
+	ranges := make([]acceptRange, 0, len(parts))
+	for _, part := range parts {
+		params := strings.Split(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+
+		r := acceptRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if !strings.EqualFold(strings.TrimSpace(key), "q") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || q < 0 || q > 1 {
+				ok = false
+			}
+			r.q = q
+			break
+		}
+		if ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// acceptQuality returns the q-value the most specific matching range assigns to mime,
+// along with that specificity: 2 for type/subtype, 1 for type/* and 0 for */*.
+func acceptQuality(ranges []acceptRange, mime string) (float64, int) {
+	typ, subtype, _ := strings.Cut(mime, "/")
+	q, spec := 0.0, -1
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*":
+			s = 0
+		default:
+			continue
+		}
+		if s > spec {
+			q, spec = r.q, s
+		}
+	}
+	return q, spec
+}
+
+// mediaType returns the lower-cased type/subtype of a Content-Type like value.
+func mediaType(mime string) string {
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = mime[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mime))
+}
+
+// addVary adds value to the Vary header unless it is already listed.
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
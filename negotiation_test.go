@@ -0,0 +1,73 @@
+package gem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	offered := []string{MIMEJSON, MIMEXML, MIMEYAML}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", MIMEJSON},
+		{"application/xml", MIMEXML},
+		{"application/xml;q=0.5, application/x-yaml", MIMEYAML},
+		{"text/*, application/*;q=0.2", MIMEJSON},
+		{"application/*;q=0.2, application/xml;q=0.9", MIMEXML},
+		{"*/*;q=0.1, application/json;q=0", MIMEXML},
+		{"text/html", ""},
+		{"application/json;q=abc, application/xml", MIMEXML},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		c := &Context{Request: req}
+		if got := c.NegotiateFormat(offered...); got != tt.want {
+			t.Errorf("Accept %q: got %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	server := New()
+	server.GET("/", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			Offered: []string{MIMEJSON, MIMEXML},
+			Data:    H{"hello": "world"},
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != `{"hello":"world"}` {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if vary := w.Header().Get("Vary"); vary != "Accept" {
+		t.Fatalf("expected Vary: Accept, got %q", vary)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "image/png")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected 406, got %d", w.Code)
+	}
+
+	server.GET("/unregistered", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{Offered: []string{"text/html"}, Data: "hi"})
+	})
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unregistered", nil))
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("expected 406 for unregistered offers, got %d", w.Code)
+	}
+}
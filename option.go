@@ -4,6 +4,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/crazyfrankie/gem/binding"
+	"github.com/crazyfrankie/gem/codec"
 	"github.com/crazyfrankie/gem/config"
 )
 
@@ -143,3 +145,56 @@ func WithH2C(enable bool) config.Option {
 		o.H2C = enable
 	}}
 }
+
+// WithMaxRequestBodySize sets the server-wide limit of request bodies, 0 means no limit.
+//
+// Reading past the limit fails with an *http.MaxBytesError, which bindings report
+// as binding.ErrBodyTooLarge and answer with 413. Use MaxBodySize to change it per route.
+func WithMaxRequestBodySize(n int64) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.MaxRequestBodySize = n
+	}}
+}
+
+// WithRequestDecompression sets whether request bodies sent with
+// Content-Encoding gzip or deflate are decoded before handlers read them. It is disabled by default,
+// handlers then see bodies as they were sent.
+func WithRequestDecompression(b bool) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.DecompressRequestBody = b
+	}}
+}
+
+// WithDecompressionLimits bounds decoded request bodies to maxSize bytes and to
+// maxRatio times the bytes received, protecting against decompression bombs.
+// The defaults are 32 MB and 100, 0 disables a limit.
+func WithDecompressionLimits(maxSize, maxRatio int64) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.MaxDecompressedBodySize = maxSize
+		o.MaxDecompressionRatio = maxRatio
+	}}
+}
+
+// WithJSONDecoding sets the options of the JSON binding used by the binding methods of Context,
+// such as rejecting unknown fields or decoding numbers as json.Number.
+func WithJSONDecoding(opts binding.JSONOptions) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.JSONDecoding = opts
+	}}
+}
+
+// WithCodecs sets the registry providing the JSON, XML and YAML codecs used by renders,
+// bindings and error responses. By default every server gets its own codec.NewRegistry().
+func WithCodecs(r *codec.Registry) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.Codecs = r
+	}}
+}
+
+// WithSecureJSONPrefix sets the prefix SecureJSON writes before top-level arrays,
+// "while(1);" by default.
+func WithSecureJSONPrefix(prefix string) config.Option {
+	return config.Option{F: func(o *config.Options) {
+		o.SecureJSONPrefix = prefix
+	}}
+}
@@ -0,0 +1,32 @@
+package render
+
+import (
+	"net/http"
+
+	"github.com/crazyfrankie/gem/internal/cbor"
+)
+
+// CBOR contains the given interface object.
+type CBOR struct {
+	Data any
+}
+
+var cborContentType = []string{"application/cbor"}
+
+// Render (CBOR) encodes the given interface object and writes data with custom ContentType.
+func (c CBOR) Render(writer http.ResponseWriter) error {
+	c.WriteContentType(writer)
+
+	bytes, err := cbor.Marshal(c.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(bytes)
+	return err
+}
+
+// WriteContentType (CBOR) writes CBOR ContentType.
+func (c CBOR) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, cborContentType)
+}
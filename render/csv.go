@@ -0,0 +1,154 @@
+package render
+
+import (
+	"context"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"iter"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/crazyfrankie/gem/internal/structfields"
+)
+
+// CSV streams the elements of Seq, or the values received from Chan, as CSV rows,
+// flushing the response after each of them, like NDJSON.
+//
+// T is either []string, written as is, or a struct or pointer to struct whose fields
+// make the columns: a field is named by its csv tag, then its json tag, then its name,
+// and "-" leaves it out. The header row lists those names unless NoHeader is set.
+type CSV[T any] struct {
+	Seq     iter.Seq[T]
+	Chan    <-chan T
+	Context context.Context
+	// Comma is the field delimiter, 0 means ','.
+	Comma    rune
+	NoHeader bool
+}
+
+var csvContentType = []string{"text/csv; charset=utf-8"}
+
+// Render (CSV) writes the header and rows with custom ContentType.
+func (r CSV[T]) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	cw := csv.NewWriter(w)
+	if r.Comma != 0 {
+		cw.Comma = r.Comma
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	isStrings := t == reflect.TypeOf([]string(nil))
+	var fields []structfields.Field
+	if !isStrings {
+		st := t
+		if st.Kind() == reflect.Pointer {
+			st = st.Elem()
+		}
+		if st.Kind() != reflect.Struct {
+			return fmt.Errorf("render: CSV rows must be []string or structs, not %s", t)
+		}
+		fields = structfields.Of(st, "csv")
+		if len(fields) == 0 {
+			return fmt.Errorf("render: CSV rows of type %s have no exported fields", t)
+		}
+		if !r.NoHeader {
+			header := make([]string, len(fields))
+			for i, f := range fields {
+				header[i] = f.Name
+			}
+			if err := r.writeRow(w, cw, header); err != nil {
+				return err
+			}
+		}
+	}
+
+	var record []string
+	return each(r.Context, r.Seq, r.Chan, func(v T) error {
+		if isStrings {
+			return r.writeRow(w, cw, any(v).([]string))
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return nil
+			}
+			rv = rv.Elem()
+		}
+		record = record[:0]
+		for _, f := range fields {
+			fv, ok := structfields.FieldByIndex(rv, f.Index, false)
+			if !ok {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, csvValue(fv))
+		}
+		return r.writeRow(w, cw, record)
+	})
+}
+
+func (r CSV[T]) writeRow(w http.ResponseWriter, cw *csv.Writer, record []string) error {
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	flush(w)
+	return nil
+}
+
+// WriteContentType (CSV) writes CSV ContentType.
+func (r CSV[T]) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, csvContentType)
+}
+
+// WithContext (CSV) returns r stopping with ctx unless it already has a Context.
+func (r CSV[T]) WithContext(ctx context.Context) Render {
+	if r.Context == nil {
+		r.Context = ctx
+	}
+	return r
+}
+
+// csvValue formats a field: times as RFC 3339, encoding.TextMarshaler and fmt.Stringer
+// with their own methods, nil pointers as empty cells.
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.CanInterface() {
+		switch x := v.Interface().(type) {
+		case time.Time:
+			return x.Format(time.RFC3339)
+		case encoding.TextMarshaler:
+			b, err := x.MarshalText()
+			if err == nil {
+				return string(b)
+			}
+		case fmt.Stringer:
+			return x.String()
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,265 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Delims represents a set of Left and Right delimiters for HTML template rendering.
+type Delims struct {
+	// Left delimiter, defaults to {{.
+	Left string
+	// Right delimiter, defaults to }}.
+	Right string
+}
+
+// HTMLRender produces the render of the template name executed with data.
+type HTMLRender interface {
+	Instance(name string, data any) Render
+}
+
+// HTML contains the template to execute and the data to execute it with.
+type HTML struct {
+	Template *template.Template
+	// Name is the template of Template to execute, empty executes Template itself.
+	Name string
+	Data any
+}
+
+var htmlContentType = []string{"text/html; charset=utf-8"}
+
+// bufferPool holds the buffers templates are executed into and custom JSON codecs indent into.
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// Render (HTML) executes the template into a buffer and writes it with custom ContentType
+// once the execution succeeded, so a failing template writes nothing.
+func (r HTML) Render(w http.ResponseWriter) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			bufferPool.Put(buf)
+		}
+	}()
+	buf.Reset()
+
+	var err error
+	if r.Name == "" {
+		err = r.Template.Execute(buf, r.Data)
+	} else {
+		err = r.Template.ExecuteTemplate(buf, r.Name, r.Data)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.WriteContentType(w)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// WriteContentType (HTML) writes HTML ContentType.
+func (r HTML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, htmlContentType)
+}
+
+// errorRender is returned by HTMLTemplates.Instance when the templates fail to reload.
+type errorRender struct {
+	err error
+}
+
+func (r errorRender) Render(http.ResponseWriter) error {
+	return r.err
+}
+
+func (r errorRender) WriteContentType(http.ResponseWriter) {}
+
+// HTMLTemplates is an HTMLRender parsing the template files matched by Patterns.
+//
+// Files inside a directory named layouts or partials are shared: they are parsed into
+// every page, which is every other file, parsed on its own so that pages may each define
+// the blocks of a layout:
+//
+//	layouts/base.html: <html><body>{{block "content" .}}{{end}}</body></html>
+//	index.html:        {{template "base.html" .}}{{define "content"}}Hi {{.}}{{end}}
+//
+// Templates are named after the base name of their file, so two files matched by
+// Patterns may not share a base name.
+type HTMLTemplates struct {
+	// FS holds the template files, nil means the operating system's file system.
+	FS       fs.FS
+	Patterns []string
+	FuncMap  template.FuncMap
+	Delims   Delims
+	// Reload parses the files again whenever one of them is added, removed or modified.
+	// The files are globbed, statted and parsed without holding up renders of other requests.
+	Reload bool
+
+	mu     sync.RWMutex
+	stamp  string
+	shared *template.Template
+	pages  map[string]*template.Template
+}
+
+// Load parses the template files.
+func (t *HTMLTemplates) Load() error {
+	return t.load()
+}
+
+// Instance (HTMLTemplates) returns the render of the page or shared template name.
+func (t *HTMLTemplates) Instance(name string, data any) Render {
+	t.mu.RLock()
+	loaded := t.shared != nil
+	t.mu.RUnlock()
+	if !loaded || t.Reload {
+		if err := t.load(); err != nil {
+			return errorRender{err: err}
+		}
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if page, ok := t.pages[name]; ok {
+		return HTML{Template: page, Name: name, Data: data}
+	}
+	return HTML{Template: t.shared, Name: name, Data: data}
+}
+
+// load parses the files unless they are unchanged since the last load.
+// Only swapping in the parsed templates holds the lock.
+func (t *HTMLTemplates) load() error {
+	files, stamp, err := t.files()
+	if err != nil {
+		return err
+	}
+	t.mu.RLock()
+	current := t.shared != nil && stamp == t.stamp
+	t.mu.RUnlock()
+	if current {
+		return nil
+	}
+
+	shared, pages, err := t.parseFiles(files)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.shared, t.pages, t.stamp = shared, pages, stamp
+	t.mu.Unlock()
+	return nil
+}
+
+// parseFiles parses the shared templates and then every page on top of a copy of them.
+func (t *HTMLTemplates) parseFiles(files []string) (*template.Template, map[string]*template.Template, error) {
+	names := make(map[string]string, len(files))
+	for _, file := range files {
+		name := baseName(file)
+		if other, ok := names[name]; ok {
+			return nil, nil, fmt.Errorf("render: templates %s and %s are both named %q", other, file, name)
+		}
+		names[name] = file
+	}
+
+	shared := template.New("").Delims(t.Delims.Left, t.Delims.Right).Funcs(t.FuncMap)
+	var pages []string
+	for _, file := range files {
+		if !isSharedTemplate(file) {
+			pages = append(pages, file)
+			continue
+		}
+		if err := t.parse(shared, file); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	pageSets := make(map[string]*template.Template, len(pages))
+	for _, file := range pages {
+		set, err := shared.Clone()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := t.parse(set, file); err != nil {
+			return nil, nil, err
+		}
+		pageSets[baseName(file)] = set
+	}
+	return shared, pageSets, nil
+}
+
+func (t *HTMLTemplates) parse(set *template.Template, file string) error {
+	var content []byte
+	var err error
+	if t.FS != nil {
+		content, err = fs.ReadFile(t.FS, file)
+	} else {
+		content, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = set.New(baseName(file)).Parse(string(content))
+	return err
+}
+
+// files returns the files matched by Patterns and a stamp that changes with any of them.
+func (t *HTMLTemplates) files() ([]string, string, error) {
+	var files []string
+	var stamp strings.Builder
+	seen := make(map[string]bool)
+	for _, pattern := range t.Patterns {
+		var matches []string
+		var err error
+		if t.FS != nil {
+			matches, err = fs.Glob(t.FS, pattern)
+		} else {
+			matches, err = filepath.Glob(pattern)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if len(matches) == 0 {
+			return nil, "", fmt.Errorf("render: pattern %q matches no files", pattern)
+		}
+
+		for _, file := range matches {
+			var info fs.FileInfo
+			if t.FS != nil {
+				info, err = fs.Stat(t.FS, file)
+			} else {
+				info, err = os.Stat(file)
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			if info.IsDir() || seen[file] {
+				continue
+			}
+			seen[file] = true
+			files = append(files, file)
+			fmt.Fprintf(&stamp, "%s %d %d\n", file, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return files, stamp.String(), nil
+}
+
+func baseName(file string) string {
+	return path.Base(filepath.ToSlash(file))
+}
+
+// isSharedTemplate reports whether file is inside a layouts or partials directory.
+func isSharedTemplate(file string) bool {
+	dirs := strings.Split(path.Dir(filepath.ToSlash(file)), "/")
+	for _, dir := range dirs {
+		if dir == "layouts" || dir == "partials" {
+			return true
+		}
+	}
+	return false
+}
@@ -1,31 +1,355 @@
 package render
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/crazyfrankie/gem/codec"
 )
 
 // JSON　contains the given interface object.
 type JSON struct {
 	Data any
+	// Codec encodes Data, nil means codec.JSON.
+	Codec codec.Codec
+}
+
+// IndentedJSON contains the given interface object, rendered with 4-space indentation.
+type IndentedJSON struct {
+	Data  any
+	Codec codec.Codec
+}
+
+// SecureJSON contains the given interface object. Prefix is written before top-level arrays,
+// so that a page loading the response as a script cannot read it.
+type SecureJSON struct {
+	Prefix string
+	Data   any
+	Codec  codec.Codec
+}
+
+// JSONP contains the given interface object, wrapped in a call of Callback.
+// An empty Callback renders plain JSON.
+type JSONP struct {
+	Callback string
+	Data     any
+	Codec    codec.Codec
+}
+
+// AsciiJSON contains the given interface object, rendered with every non-ASCII
+// character escaped as \uXXXX.
+type AsciiJSON struct {
+	Data  any
+	Codec codec.Codec
+}
+
+// PureJSON contains the given interface object, rendered without escaping HTML characters such as <.
+type PureJSON struct {
+	Data  any
+	Codec codec.Codec
 }
 
-var jsonContentType = []string{"application/json; charset=utf-8"}
+// ErrInvalidCallback is returned by JSONP for callbacks that are not JavaScript identifiers.
+var ErrInvalidCallback = errors.New("render: invalid JSONP callback")
+
+// callbackRe matches dotted JavaScript identifiers such as jQuery.cb_1.
+var callbackRe = regexp.MustCompile(`^[\p{L}_$][\p{L}\p{N}_$]*(\.[\p{L}_$][\p{L}\p{N}_$]*)*$`)
+
+// ValidCallback reports whether callback can be used as a JSONP callback.
+func ValidCallback(callback string) bool {
+	return len(callback) <= 128 && callbackRe.MatchString(callback)
+}
+
+var (
+	jsonContentType      = []string{"application/json; charset=utf-8"}
+	jsonpContentType     = []string{"application/javascript; charset=utf-8"}
+	jsonASCIIContentType = []string{"application/json"}
+)
+
+// maxPooledBuffer is the capacity above which encoding buffers are not kept for reuse.
+const maxPooledBuffer = 64 << 10
+
+// jsonEncoder passes what codec.JSON encodes on to write, the encoder is kept bound to it.
+// encoding/json builds a value in a buffer of its own, pooled whatever its size, and hands it
+// to its writer in a single Write once encoding succeeded. So write gets the whole encoding
+// without it being copied, and a value that fails to encode writes nothing.
+type jsonEncoder struct {
+	write func([]byte) error
+	std   *json.Encoder
+}
+
+func (e *jsonEncoder) Write(b []byte) (int, error) {
+	if err := e.write(bytes.TrimSuffix(b, []byte{'\n'})); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+var jsonEncoderPool = sync.Pool{New: func() any {
+	e := new(jsonEncoder)
+	e.std = json.NewEncoder(e)
+	return e
+}}
+
+// encodeJSON encodes data with c, escaping HTML when escapeHTML is set and indenting with
+// indent when it is not empty, and passes the encoding, without a trailing newline, to write.
+// codec.JSON encodes with a pooled encoder, other codecs are used through Marshal
+// and escape HTML as they do by default.
+func encodeJSON(c codec.Codec, data any, escapeHTML bool, indent string, write func([]byte) error) error {
+	if c != nil && c != codec.JSON {
+		b, err := c.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if indent == "" {
+			return write(bytes.TrimSuffix(b, []byte{'\n'}))
+		}
+		buf := bufferPool.Get().(*bytes.Buffer)
+		defer func() {
+			if buf.Cap() <= maxPooledBuffer {
+				bufferPool.Put(buf)
+			}
+		}()
+		buf.Reset()
+		if json.Indent(buf, b, "", indent) == nil {
+			b = buf.Bytes()
+		}
+		return write(bytes.TrimSuffix(b, []byte{'\n'}))
+	}
+
+	e := jsonEncoderPool.Get().(*jsonEncoder)
+	defer func() {
+		e.write = nil
+		jsonEncoderPool.Put(e)
+	}()
+	e.write = write
+	e.std.SetEscapeHTML(escapeHTML)
+	e.std.SetIndent("", indent)
+	return e.std.Encode(data)
+}
 
 // Render (JSON) writes data with custom ContentType.
 func (j JSON) Render(writer http.ResponseWriter) error {
-	writeContentType(writer, jsonContentType)
+	j.WriteContentType(writer)
+	return encodeJSON(j.Codec, j.Data, true, "", func(b []byte) error {
+		_, err := writer.Write(b)
+		return err
+	})
+}
 
-	bytes, err := json.Marshal(j.Data)
-	if err != nil {
+// WriteContentType (JSON) writes custom ContentType.
+func (j JSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// Render (IndentedJSON) marshals the given interface object and writes it with custom ContentType.
+func (r IndentedJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return encodeJSON(r.Codec, r.Data, true, "    ", func(b []byte) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// WriteContentType (IndentedJSON) writes JSON ContentType.
+func (r IndentedJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// Render (SecureJSON) marshals the given interface object and writes it with custom ContentType.
+func (r SecureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return encodeJSON(r.Codec, r.Data, true, "", func(b []byte) error {
+		if bytes.HasPrefix(b, []byte("[")) && bytes.HasSuffix(b, []byte("]")) {
+			if _, err := w.Write([]byte(r.Prefix)); err != nil {
+				return err
+			}
+		}
+		_, err := w.Write(b)
 		return err
+	})
+}
+
+// WriteContentType (SecureJSON) writes JSON ContentType.
+func (r SecureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// Render (JSONP) marshals the given interface object and writes it and its callback with custom ContentType.
+// It returns ErrInvalidCallback without writing anything when the callback is not valid.
+func (r JSONP) Render(w http.ResponseWriter) error {
+	if r.Callback != "" && !ValidCallback(r.Callback) {
+		return fmt.Errorf("%w: %q", ErrInvalidCallback, r.Callback)
+	}
+	r.WriteContentType(w)
+	return encodeJSON(r.Codec, r.Data, true, "", func(b []byte) error {
+		if r.Callback == "" {
+			_, err := w.Write(b)
+			return err
+		}
+		// The leading comment keeps the response from starting with bytes chosen
+		// by the client, such as the header of a Flash file.
+		if _, err := io.WriteString(w, "/**/"+r.Callback+"("); err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, ");")
+		return err
+	})
+}
+
+// WriteContentType (JSONP) writes Javascript ContentType, or JSON ContentType without a callback.
+func (r JSONP) WriteContentType(w http.ResponseWriter) {
+	if r.Callback == "" {
+		writeContentType(w, jsonContentType)
+		return
 	}
+	writeContentType(w, jsonpContentType)
+}
+
+// Render (AsciiJSON) marshals the given interface object and writes it with custom ContentType.
+func (r AsciiJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return encodeJSON(r.Codec, r.Data, true, "", func(b []byte) error {
+		return writeASCII(w, b)
+	})
+}
+
+// WriteContentType (AsciiJSON) writes JSON ContentType.
+func (r AsciiJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonASCIIContentType)
+}
 
-	_, err = writer.Write(bytes)
+// writeASCII writes b to w with its non-ASCII characters, which only appear in JSON strings,
+// escaped as \uXXXX, using surrogate pairs beyond the Basic Multilingual Plane. Runs of ASCII
+// are written as they are and escapes are gathered in a small buffer, so b is not copied.
+func writeASCII(w io.Writer, b []byte) error {
+	var esc []byte
+	for i := 0; i < len(b); {
+		if b[i] < utf8.RuneSelf {
+			j := i + 1
+			for j < len(b) && b[j] < utf8.RuneSelf {
+				j++
+			}
+			if err := flushEscapes(w, &esc); err != nil {
+				return err
+			}
+			if _, err := w.Write(b[i:j]); err != nil {
+				return err
+			}
+			i = j
+			continue
+		}
+
+		if esc == nil {
+			esc = make([]byte, 0, 512)
+		} else if len(esc)+12 > cap(esc) {
+			if err := flushEscapes(w, &esc); err != nil {
+				return err
+			}
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		i += size
+		if r > 0xFFFF {
+			r -= 0x10000
+			esc = appendUnicodeEscape(esc, 0xD800+(r>>10))
+			esc = appendUnicodeEscape(esc, 0xDC00+(r&0x3FF))
+		} else {
+			esc = appendUnicodeEscape(esc, r)
+		}
+	}
+	return flushEscapes(w, &esc)
+}
+
+// flushEscapes writes the escapes gathered in esc and empties it.
+func flushEscapes(w io.Writer, esc *[]byte) error {
+	if len(*esc) == 0 {
+		return nil
+	}
+	_, err := w.Write(*esc)
+	*esc = (*esc)[:0]
 	return err
 }
 
-// WriteContentType (JSON) writes custom ContentType.
-func (j JSON) WriteContentType(w http.ResponseWriter) {
+const hexDigits = "0123456789abcdef"
+
+func appendUnicodeEscape(b []byte, r rune) []byte {
+	return append(b, '\\', 'u', hexDigits[r>>12&0xF], hexDigits[r>>8&0xF], hexDigits[r>>4&0xF], hexDigits[r&0xF])
+}
+
+// Render (PureJSON) writes custom ContentType and encodes the given interface object.
+func (r PureJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return encodeJSON(r.Codec, r.Data, false, "", func(b []byte) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// WriteContentType (PureJSON) writes custom ContentType.
+func (r PureJSON) WriteContentType(w http.ResponseWriter) {
 	writeContentType(w, jsonContentType)
 }
+
+// CodecName (JSON) returns codec.JSONName.
+func (j JSON) CodecName() string { return codec.JSONName }
+
+// CodecName (IndentedJSON) returns codec.JSONName.
+func (r IndentedJSON) CodecName() string { return codec.JSONName }
+
+// CodecName (SecureJSON) returns codec.JSONName.
+func (r SecureJSON) CodecName() string { return codec.JSONName }
+
+// CodecName (JSONP) returns codec.JSONName.
+func (r JSONP) CodecName() string { return codec.JSONName }
+
+// CodecName (AsciiJSON) returns codec.JSONName.
+func (r AsciiJSON) CodecName() string { return codec.JSONName }
+
+// CodecName (PureJSON) returns codec.JSONName.
+func (r PureJSON) CodecName() string { return codec.JSONName }
+
+// WithCodec (JSON) returns j encoding with c unless it already has a Codec.
+func (j JSON) WithCodec(c codec.Codec) Render {
+	j.Codec = codecOr(j.Codec, c)
+	return j
+}
+
+// WithCodec (IndentedJSON) returns r encoding with c unless it already has a Codec.
+func (r IndentedJSON) WithCodec(c codec.Codec) Render {
+	r.Codec = codecOr(r.Codec, c)
+	return r
+}
+
+// WithCodec (SecureJSON) returns r encoding with c unless it already has a Codec.
+func (r SecureJSON) WithCodec(c codec.Codec) Render {
+	r.Codec = codecOr(r.Codec, c)
+	return r
+}
+
+// WithCodec (JSONP) returns r encoding with c unless it already has a Codec.
+func (r JSONP) WithCodec(c codec.Codec) Render {
+	r.Codec = codecOr(r.Codec, c)
+	return r
+}
+
+// WithCodec (AsciiJSON) returns r encoding with c unless it already has a Codec.
+func (r AsciiJSON) WithCodec(c codec.Codec) Render {
+	r.Codec = codecOr(r.Codec, c)
+	return r
+}
+
+// WithCodec (PureJSON) returns r encoding with c unless it already has a Codec.
+func (r PureJSON) WithCodec(c codec.Codec) Render {
+	r.Codec = codecOr(r.Codec, c)
+	return r
+}
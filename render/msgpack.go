@@ -0,0 +1,32 @@
+package render
+
+import (
+	"net/http"
+
+	"github.com/crazyfrankie/gem/internal/msgpack"
+)
+
+// MsgPack contains the given interface object.
+type MsgPack struct {
+	Data any
+}
+
+var msgpackContentType = []string{"application/msgpack"}
+
+// Render (MsgPack) encodes the given interface object and writes data with custom ContentType.
+func (m MsgPack) Render(writer http.ResponseWriter) error {
+	m.WriteContentType(writer)
+
+	bytes, err := msgpack.Marshal(m.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(bytes)
+	return err
+}
+
+// WriteContentType (MsgPack) writes MsgPack ContentType.
+func (m MsgPack) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, msgpackContentType)
+}
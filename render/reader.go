@@ -0,0 +1,43 @@
+package render
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Reader contains the IO reader and its length, and custom ContentType and other headers.
+type Reader struct {
+	ContentType string
+	// ContentLength is sent as the Content-Length header unless it is negative.
+	ContentLength int64
+	Reader        io.Reader
+	Headers       map[string]string
+}
+
+// Render (Reader) writes data with custom ContentType and headers,
+// copying the reader to the client without buffering it in memory.
+func (r Reader) Render(w http.ResponseWriter) (err error) {
+	r.WriteContentType(w)
+	if r.ContentLength >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(r.ContentLength, 10))
+	}
+	r.writeHeaders(w)
+	_, err = io.Copy(w, r.Reader)
+	return
+}
+
+// WriteContentType (Reader) writes custom ContentType.
+func (r Reader) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, []string{r.ContentType})
+}
+
+// writeHeaders writes custom Header.
+func (r Reader) writeHeaders(w http.ResponseWriter) {
+	header := w.Header()
+	for k, v := range r.Headers {
+		if header.Get(k) == "" {
+			header.Set(k, v)
+		}
+	}
+}
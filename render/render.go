@@ -1,6 +1,10 @@
 package render
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/crazyfrankie/gem/codec"
+)
 
 type Render interface {
 	Render(http.ResponseWriter) error
@@ -8,14 +12,51 @@ type Render interface {
 	WriteContentType(w http.ResponseWriter)
 }
 
+// CodecRender is implemented by renders that encode their data with a codec.Codec.
+// Context.Render hands them the codec its server registers under CodecName.
+type CodecRender interface {
+	Render
+	CodecName() string
+	// WithCodec returns the render encoding with c, unless a codec is already set.
+	WithCodec(c codec.Codec) Render
+}
+
 var (
 	_ Render = (*JSON)(nil)
+	_ Render = (*IndentedJSON)(nil)
+	_ Render = (*SecureJSON)(nil)
+	_ Render = (*JSONP)(nil)
+	_ Render = (*AsciiJSON)(nil)
+	_ Render = (*PureJSON)(nil)
 	_ Render = (*Data)(nil)
 	_ Render = (*String)(nil)
 	_ Render = (*ProtoBuf)(nil)
+	_ Render = (*MsgPack)(nil)
+	_ Render = (*CBOR)(nil)
 	_ Render = (*Redirect)(nil)
 	_ Render = (*YAML)(nil)
 	_ Render = (*XML)(nil)
+	_ Render = (*SSEvent)(nil)
+	_ Render = (*Reader)(nil)
+	_ Render = (*HTML)(nil)
+
+	_ HTMLRender = (*HTMLTemplates)(nil)
+
+	_ CodecRender   = (*NDJSON[any])(nil)
+	_ CodecRender   = (*JSONArrayStream[any])(nil)
+	_ ContextRender = (*NDJSON[any])(nil)
+	_ ContextRender = (*JSONArrayStream[any])(nil)
+	_ ContextRender = (*CSV[any])(nil)
+
+	_ CodecRender = (*JSON)(nil)
+	_ CodecRender = (*IndentedJSON)(nil)
+	_ CodecRender = (*SecureJSON)(nil)
+	_ CodecRender = (*JSONP)(nil)
+	_ CodecRender = (*AsciiJSON)(nil)
+	_ CodecRender = (*PureJSON)(nil)
+	_ CodecRender = (*XML)(nil)
+	_ CodecRender = (*YAML)(nil)
+	_ CodecRender = (*SSEvent)(nil)
 )
 
 func writeContentType(w http.ResponseWriter, value []string) {
@@ -24,3 +65,10 @@ func writeContentType(w http.ResponseWriter, value []string) {
 		header["Content-Type"] = value
 	}
 }
+
+func codecOr(c, def codec.Codec) codec.Codec {
+	if c == nil {
+		return def
+	}
+	return c
+}
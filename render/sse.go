@@ -0,0 +1,124 @@
+package render
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/crazyfrankie/gem/codec"
+)
+
+// SSEvent contains a single Server-Sent Event.
+// See https://html.spec.whatwg.org/multipage/server-sent-events.html
+type SSEvent struct {
+	Id    string
+	Event string
+	// Retry is the reconnection time in milliseconds the client should use, zero leaves it unchanged.
+	Retry uint
+	// Data is written as is when it is a string or []byte, and encoded as JSON otherwise.
+	Data any
+	// Codec encodes Data, nil means codec.JSON.
+	Codec codec.Codec
+}
+
+var sseContentType = []string{"text/event-stream"}
+
+// fieldReplacer strips the characters that would end an id or event field early.
+var fieldReplacer = strings.NewReplacer("\r\n", "", "\n", "", "\r", "", "\x00", "")
+
+// Render (SSEvent) writes the event framing with custom ContentType.
+func (r SSEvent) Render(writer http.ResponseWriter) error {
+	r.WriteContentType(writer)
+	return r.Encode(writer)
+}
+
+// WriteContentType (SSEvent) writes the event-stream ContentType and disables caching.
+func (r SSEvent) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, sseContentType)
+	header := w.Header()
+	if len(header["Cache-Control"]) == 0 {
+		header.Set("Cache-Control", "no-cache")
+	}
+}
+
+// Encode writes the event framing of r to w.
+// Multi-line data is split into one data field per line.
+func (r SSEvent) Encode(w io.Writer) error {
+	var buf strings.Builder
+	if r.Id != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(fieldReplacer.Replace(r.Id))
+		buf.WriteByte('\n')
+	}
+	if r.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(fieldReplacer.Replace(r.Event))
+		buf.WriteByte('\n')
+	}
+	if r.Retry > 0 {
+		buf.WriteString("retry: ")
+		buf.WriteString(strconv.FormatUint(uint64(r.Retry), 10))
+		buf.WriteByte('\n')
+	}
+
+	data, err := sseData(r.Data, codecOr(r.Codec, codec.JSON))
+	if err != nil {
+		return err
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		buf.WriteString("data: ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	_, err = io.WriteString(w, buf.String())
+	return err
+}
+
+// CodecName (SSEvent) returns codec.JSONName.
+func (r SSEvent) CodecName() string {
+	return codec.JSONName
+}
+
+// WithCodec (SSEvent) returns r encoding with c unless it already has a Codec.
+func (r SSEvent) WithCodec(c codec.Codec) Render {
+	if r.Codec == nil {
+		r.Codec = c
+	}
+	return r
+}
+
+// WriteSSEComment writes a comment line, which clients ignore.
+// It is commonly sent periodically to keep idle connections from being closed by proxies.
+func WriteSSEComment(w io.Writer, comment string) error {
+	var buf strings.Builder
+	for _, line := range strings.Split(strings.ReplaceAll(comment, "\r", ""), "\n") {
+		buf.WriteString(": ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+func sseData(data any, c codec.Codec) (string, error) {
+	switch v := data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	b, err := c.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,190 @@
+package render
+
+import (
+	"context"
+	"iter"
+	"net/http"
+
+	"github.com/crazyfrankie/gem/codec"
+)
+
+// ContextRender is implemented by renders that stop once a context is done.
+// Context.Render hands them the request context, unless they already have one.
+type ContextRender interface {
+	Render
+	WithContext(ctx context.Context) Render
+}
+
+// NDJSON streams every element of Seq, or every value received from Chan until it is closed,
+// as a line of JSON, flushing the response after each of them.
+// The stream stops without error once Context is done, such as when the client disconnects.
+//
+// Stopping does not drain Chan nor tell its producer, and Seq is only checked between
+// elements: a goroutine sending on Chan, or a Seq waiting for its next element, must also
+// select on the request context, otherwise it blocks forever once the client is gone:
+//
+//	ctx := c.Request.Context()
+//	go func() {
+//		defer close(ch)
+//		for ev := range events {
+//			select {
+//			case ch <- ev:
+//			case <-ctx.Done():
+//				return
+//			}
+//		}
+//	}()
+type NDJSON[T any] struct {
+	Seq  iter.Seq[T]
+	Chan <-chan T
+	// Context stops the stream when done, nil means it is never stopped.
+	Context context.Context
+	// Codec encodes the elements, nil means codec.JSON.
+	Codec codec.Codec
+}
+
+// JSONArrayStream streams the elements of Seq, or the values received from Chan,
+// as a single JSON array, like NDJSON, with the same requirements on producers.
+// A stream stopped early leaves the array unterminated.
+type JSONArrayStream[T any] struct {
+	Seq     iter.Seq[T]
+	Chan    <-chan T
+	Context context.Context
+	Codec   codec.Codec
+}
+
+var ndjsonContentType = []string{"application/x-ndjson"}
+
+// Render (NDJSON) writes every element followed by a newline with custom ContentType.
+func (r NDJSON[T]) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return each(r.Context, r.Seq, r.Chan, func(v T) error {
+		err := encodeJSON(r.Codec, v, true, "", func(b []byte) error {
+			_, err := w.Write(append(b, '\n'))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		flush(w)
+		return nil
+	})
+}
+
+// WriteContentType (NDJSON) writes NDJSON ContentType.
+func (r NDJSON[T]) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, ndjsonContentType)
+}
+
+// Render (JSONArrayStream) writes the elements between brackets with custom ContentType.
+func (r JSONArrayStream[T]) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	if _, err := w.Write([]byte{'['}); err != nil {
+		return err
+	}
+	flush(w)
+
+	first := true
+	err := each(r.Context, r.Seq, r.Chan, func(v T) error {
+		err := encodeJSON(r.Codec, v, true, "", func(b []byte) error {
+			if !first {
+				if _, err := w.Write([]byte{','}); err != nil {
+					return err
+				}
+			}
+			_, err := w.Write(b)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		first = false
+		flush(w)
+		return nil
+	})
+	if err != nil || r.Context != nil && r.Context.Err() != nil {
+		return err
+	}
+	_, err = w.Write([]byte{']'})
+	return err
+}
+
+// WriteContentType (JSONArrayStream) writes JSON ContentType.
+func (r JSONArrayStream[T]) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, jsonContentType)
+}
+
+// CodecName (NDJSON) returns codec.JSONName.
+func (r NDJSON[T]) CodecName() string { return codec.JSONName }
+
+// CodecName (JSONArrayStream) returns codec.JSONName.
+func (r JSONArrayStream[T]) CodecName() string { return codec.JSONName }
+
+// WithCodec (NDJSON) returns r encoding with c unless it already has a Codec.
+func (r NDJSON[T]) WithCodec(c codec.Codec) Render {
+	r.Codec = codecOr(r.Codec, c)
+	return r
+}
+
+// WithCodec (JSONArrayStream) returns r encoding with c unless it already has a Codec.
+func (r JSONArrayStream[T]) WithCodec(c codec.Codec) Render {
+	r.Codec = codecOr(r.Codec, c)
+	return r
+}
+
+// WithContext (NDJSON) returns r stopping with ctx unless it already has a Context.
+func (r NDJSON[T]) WithContext(ctx context.Context) Render {
+	if r.Context == nil {
+		r.Context = ctx
+	}
+	return r
+}
+
+// WithContext (JSONArrayStream) returns r stopping with ctx unless it already has a Context.
+func (r JSONArrayStream[T]) WithContext(ctx context.Context) Render {
+	if r.Context == nil {
+		r.Context = ctx
+	}
+	return r
+}
+
+// each calls fn with the elements of seq, or the values received from ch when it is not nil,
+// until fn fails or ctx is done.
+func each[T any](ctx context.Context, seq iter.Seq[T], ch <-chan T, fn func(T) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ch != nil {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case v, ok := <-ch:
+				if !ok {
+					return nil
+				}
+				if err := fn(v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if seq == nil {
+		return nil
+	}
+	for v := range seq {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func flush(w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -1,13 +1,16 @@
 package render
 
 import (
-	"encoding/xml"
 	"net/http"
+
+	"github.com/crazyfrankie/gem/codec"
 )
 
 // XML contains the given interface object.
 type XML struct {
 	Data any
+	// Codec encodes Data, nil means codec.XML.
+	Codec codec.Codec
 }
 
 var xmlContentType = []string{"application/xml; charset=utf-8"}
@@ -15,10 +18,23 @@ var xmlContentType = []string{"application/xml; charset=utf-8"}
 // Render (XML) encodes the given interface object and writes data with custom ContentType.
 func (r XML) Render(w http.ResponseWriter) error {
 	r.WriteContentType(w)
-	return xml.NewEncoder(w).Encode(r.Data)
+	return codecOr(r.Codec, codec.XML).NewEncoder(w).Encode(r.Data)
 }
 
 // WriteContentType (XML) writes XML ContentType for response.
 func (r XML) WriteContentType(w http.ResponseWriter) {
 	writeContentType(w, xmlContentType)
 }
+
+// CodecName (XML) returns codec.XMLName.
+func (r XML) CodecName() string {
+	return codec.XMLName
+}
+
+// WithCodec (XML) returns r encoding with c unless it already has a Codec.
+func (r XML) WithCodec(c codec.Codec) Render {
+	if r.Codec == nil {
+		r.Codec = c
+	}
+	return r
+}
@@ -1,13 +1,16 @@
 package render
 
 import (
-	"gopkg.in/yaml.v3"
 	"net/http"
+
+	"github.com/crazyfrankie/gem/codec"
 )
 
 // YAML contains the given interface object.
 type YAML struct {
 	Data any
+	// Codec encodes Data, nil means codec.YAML.
+	Codec codec.Codec
 }
 
 var yamlContentType = []string{"application/yaml; charset=utf-8"}
@@ -16,7 +19,7 @@ var yamlContentType = []string{"application/yaml; charset=utf-8"}
 func (y YAML) Render(writer http.ResponseWriter) error {
 	y.WriteContentType(writer)
 
-	bytes, err := yaml.Marshal(y.Data)
+	bytes, err := codecOr(y.Codec, codec.YAML).Marshal(y.Data)
 	if err != nil {
 		return err
 	}
@@ -29,3 +32,16 @@ func (y YAML) Render(writer http.ResponseWriter) error {
 func (y YAML) WriteContentType(w http.ResponseWriter) {
 	writeContentType(w, yamlContentType)
 }
+
+// CodecName (YAML) returns codec.YAMLName.
+func (y YAML) CodecName() string {
+	return codec.YAMLName
+}
+
+// WithCodec (YAML) returns y encoding with c unless it already has a Codec.
+func (y YAML) WithCodec(c codec.Codec) Render {
+	if y.Codec == nil {
+		y.Codec = c
+	}
+	return y
+}
@@ -0,0 +1,58 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+)
+
+func init() {
+	gob.Register([]any{})
+	gob.Register(map[string]any{})
+}
+
+// record is the serialized form of a session.
+type record struct {
+	ID      string
+	Expires time.Time
+	Values  map[string]any
+}
+
+// EncodeValues serializes session values with encoding/gob.
+// Custom types stored in a session must be registered with gob.Register.
+func EncodeValues(values map[string]any) ([]byte, error) {
+	return encodeRecord(record{Values: values})
+}
+
+// DecodeValues is the inverse of EncodeValues.
+func DecodeValues(data []byte) (map[string]any, error) {
+	rec, err := decodeRecord(data)
+	if err != nil {
+		return nil, err
+	}
+	return rec.Values, nil
+}
+
+func encodeRecord(rec record) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rec); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeRecord(data []byte) (record, error) {
+	var rec record
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&rec); err != nil {
+		return record{}, err
+	}
+	if rec.Values == nil {
+		rec.Values = make(map[string]any)
+	}
+	return rec, nil
+}
+
+// expired reports whether rec is past its expiry at now.
+func (rec record) expired(now time.Time) bool {
+	return !rec.Expires.IsZero() && now.After(rec.Expires)
+}
@@ -0,0 +1,132 @@
+package sessions
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// maxCookieSize is the size most browsers accept for a single cookie.
+const maxCookieSize = 4096
+
+var (
+	// ErrCookieTooLarge is returned when the encoded session does not fit in a cookie.
+	ErrCookieTooLarge = errors.New("sessions: encoded session exceeds cookie size limit")
+
+	errInvalidCookie = errors.New("sessions: invalid cookie value")
+)
+
+// CookieStore keeps the whole session in the cookie itself.
+// Values are signed with HMAC-SHA256 and, when a block key is given, encrypted with AES-GCM.
+type CookieStore struct {
+	hashKey []byte
+	aead    cipher.AEAD
+}
+
+var _ Store = (*CookieStore)(nil)
+
+// NewCookieStore returns a CookieStore that signs with hashKey.
+// blockKey is optional, it must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+// Without it session values are readable, though not modifiable, by the client.
+func NewCookieStore(hashKey, blockKey []byte) (*CookieStore, error) {
+	if len(hashKey) == 0 {
+		return nil, errors.New("sessions: hash key is required")
+	}
+	s := &CookieStore{hashKey: hashKey}
+	if len(blockKey) > 0 {
+		block, err := aes.NewCipher(blockKey)
+		if err != nil {
+			return nil, err
+		}
+		if s.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Load implements Store.
+func (s *CookieStore) Load(value string) (string, map[string]any, error) {
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", nil, errInvalidCookie
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.sign(payload)) {
+		return "", nil, errInvalidCookie
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, errInvalidCookie
+	}
+	if s.aead != nil {
+		if data, err = s.open(data); err != nil {
+			return "", nil, err
+		}
+	}
+	rec, err := decodeRecord(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if rec.expired(time.Now()) || !ValidID(rec.ID) {
+		return "", nil, ErrNotFound
+	}
+	return rec.ID, rec.Values, nil
+}
+
+// Save implements Store.
+func (s *CookieStore) Save(id string, values map[string]any, maxAge time.Duration) (string, error) {
+	data, err := encodeRecord(record{ID: id, Expires: time.Now().Add(maxAge), Values: values})
+	if err != nil {
+		return "", err
+	}
+	if s.aead != nil {
+		if data, err = s.seal(data); err != nil {
+			return "", err
+		}
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	value := payload + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+	if len(value) > maxCookieSize {
+		return "", ErrCookieTooLarge
+	}
+	return value, nil
+}
+
+// Delete implements Store. A cookie session has no server-side state,
+// it is discarded by expiring the cookie.
+func (s *CookieStore) Delete(string) error {
+	return nil
+}
+
+func (s *CookieStore) sign(payload string) []byte {
+	h := hmac.New(sha256.New, s.hashKey)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+func (s *CookieStore) seal(plain []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(plain)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (s *CookieStore) open(data []byte) ([]byte, error) {
+	n := s.aead.NonceSize()
+	if len(data) < n {
+		return nil, errInvalidCookie
+	}
+	plain, err := s.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return nil, errInvalidCookie
+	}
+	return plain, nil
+}
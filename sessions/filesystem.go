@@ -0,0 +1,146 @@
+package sessions
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const filePrefix = "session_"
+
+// FileStore keeps each session in its own file under a directory.
+// Writes go through a temporary file and a rename so that a crash never leaves a torn session behind.
+type FileStore struct {
+	dir string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore returns a FileStore rooted at dir, creating it if needed.
+// Expired sessions are removed every cleanupInterval, a non-positive interval disables that.
+func NewFileStore(dir string, cleanupInterval time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	s := &FileStore{dir: dir, stop: make(chan struct{})}
+	if cleanupInterval > 0 {
+		go s.janitor(cleanupInterval)
+	}
+	return s, nil
+}
+
+// Load implements Store.
+func (s *FileStore) Load(value string) (string, map[string]any, error) {
+	if !ValidID(value) {
+		return "", nil, ErrInvalidID
+	}
+	data, err := os.ReadFile(s.path(value))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil, ErrNotFound
+		}
+		return "", nil, err
+	}
+	rec, err := decodeRecord(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if rec.expired(time.Now()) {
+		_ = os.Remove(s.path(value))
+		return "", nil, ErrNotFound
+	}
+	return value, rec.Values, nil
+}
+
+// Save implements Store.
+func (s *FileStore) Save(id string, values map[string]any, maxAge time.Duration) (string, error) {
+	if !ValidID(id) {
+		return "", ErrInvalidID
+	}
+	data, err := encodeRecord(record{ID: id, Expires: time.Now().Add(maxAge), Values: values})
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp_"+filePrefix)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), s.path(id)); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(id string) error {
+	if !ValidID(id) {
+		return ErrInvalidID
+	}
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Evict removes every expired session file.
+func (s *FileStore) Evict() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), filePrefix) {
+			continue
+		}
+		path := filepath.Join(s.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if rec, err := decodeRecord(data); err != nil || rec.expired(now) {
+			_ = os.Remove(path)
+		}
+	}
+	return nil
+}
+
+// Close stops background eviction.
+func (s *FileStore) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, filePrefix+id)
+}
+
+func (s *FileStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.Evict()
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package sessions
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps sessions in process memory.
+// Expired sessions are never returned and are evicted periodically.
+// It is suited to single-instance deployments and tests.
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type memoryItem struct {
+	// data holds the encoded values so that later mutations by the handler do not leak into the store.
+	data    []byte
+	expires time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a MemoryStore that evicts expired sessions every cleanupInterval.
+// A non-positive interval disables background eviction, expired sessions are then only
+// dropped when they are looked up.
+func NewMemoryStore(cleanupInterval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		items: make(map[string]memoryItem),
+		stop:  make(chan struct{}),
+	}
+	if cleanupInterval > 0 {
+		go s.janitor(cleanupInterval)
+	}
+	return s
+}
+
+// Load implements Store.
+func (s *MemoryStore) Load(value string) (string, map[string]any, error) {
+	if !ValidID(value) {
+		return "", nil, ErrInvalidID
+	}
+	s.mu.Lock()
+	item, ok := s.items[value]
+	if ok && time.Now().After(item.expires) {
+		delete(s.items, value)
+		ok = false
+	}
+	s.mu.Unlock()
+	if !ok {
+		return "", nil, ErrNotFound
+	}
+
+	values, err := DecodeValues(item.data)
+	if err != nil {
+		return "", nil, err
+	}
+	return value, values, nil
+}
+
+// Save implements Store.
+func (s *MemoryStore) Save(id string, values map[string]any, maxAge time.Duration) (string, error) {
+	if !ValidID(id) {
+		return "", ErrInvalidID
+	}
+	data, err := EncodeValues(values)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.items[id] = memoryItem{data: data, expires: time.Now().Add(maxAge)}
+	s.mu.Unlock()
+	return id, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.items, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of stored sessions, including expired ones not yet evicted.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// Evict removes every expired session.
+func (s *MemoryStore) Evict() {
+	now := time.Now()
+	s.mu.Lock()
+	for id, item := range s.items {
+		if now.After(item.expires) {
+			delete(s.items, id)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// Close stops background eviction.
+func (s *MemoryStore) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *MemoryStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Evict()
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/crazyfrankie/gem"
+)
+
+// DefaultKey is the Context key under which the most recently attached session is stored.
+const DefaultKey = "_gem/sessions"
+
+// flashKey is the reserved session key that holds pending flash messages.
+const flashKey = "_flash"
+
+const defaultMaxAge = 24 * time.Hour
+
+var (
+	// ErrNotFound is returned by a Store when the referenced session does not exist or has expired.
+	ErrNotFound = errors.New("sessions: session not found")
+
+	// ErrInvalidID is returned when a session ID is not in the format produced by NewID.
+	ErrInvalidID = errors.New("sessions: invalid session id")
+)
+
+// Store persists session values between requests.
+// The cookie sent to the client only carries the value returned by Save,
+// so a server-side store returns the session ID while a cookie store returns the encoded values.
+type Store interface {
+	// Load returns the ID and values of the session referenced by the cookie value.
+	// A missing or expired session yields ErrNotFound.
+	Load(value string) (id string, values map[string]any, err error)
+
+	// Save persists values for the session id for at most maxAge and
+	// returns the cookie value that references them.
+	Save(id string, values map[string]any, maxAge time.Duration) (string, error)
+
+	// Delete discards the session id.
+	Delete(id string) error
+}
+
+// Options controls the cookie that carries the session.
+type Options struct {
+	Path   string
+	Domain string
+	// MaxAge is the lifetime of both the cookie and the stored values.
+	// Zero issues a browser-session cookie while the store keeps the values for 24 hours.
+	MaxAge   time.Duration
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// DefaultOptions is used by Sessions when no Options are given.
+var DefaultOptions = Options{
+	Path:     "/",
+	MaxAge:   defaultMaxAge,
+	HttpOnly: true,
+	SameSite: http.SameSiteLaxMode,
+}
+
+// Session is the per-request view of a client session.
+type Session interface {
+	// ID returns the session ID, generating one for a new session.
+	ID() string
+	// Get returns the value stored under key.
+	Get(key string) (any, bool)
+	// Set stores val under key.
+	Set(key string, val any)
+	// Delete removes key from the session.
+	Delete(key string)
+	// Flash adds a message that is returned once by the next call to Flashes.
+	Flash(val any)
+	// Flashes returns and clears the pending flash messages.
+	Flashes() []any
+	// Regenerate moves the session to a fresh ID and discards the old one.
+	// It should be called whenever the privilege level of the session changes, such as on login.
+	Regenerate() error
+	// Destroy discards the session and expires its cookie.
+	Destroy() error
+	// Save writes the session to the store and sets the cookie.
+	// It is called automatically before the response header is written,
+	// call it explicitly to observe store errors.
+	Save() error
+}
+
+// Sessions returns a middleware that attaches the session called name to the Context.
+// If opts is nil, DefaultOptions is used.
+func Sessions(name string, store Store, opts *Options) gem.HandlerFunc {
+	o := DefaultOptions
+	if opts != nil {
+		o = *opts
+	}
+	return func(c *gem.Context) {
+		s := &session{
+			name:    name,
+			store:   store,
+			opts:    o,
+			request: c.Request,
+			writer:  c.Writer,
+		}
+		c.Set(DefaultKey, s)
+		c.Set(DefaultKey+"/"+name, s)
+
+		w := c.Writer
+		c.Writer = &sessionWriter{ResponseWriter: w, s: s}
+		defer func() {
+			c.Writer = w
+		}()
+
+		c.Next()
+
+		if !w.Written() {
+			s.autoSave()
+		}
+	}
+}
+
+// Default returns the session attached by the innermost Sessions middleware.
+func Default(c *gem.Context) Session {
+	return c.MustGet(DefaultKey).(Session)
+}
+
+// Get returns the session attached by the Sessions middleware called name.
+func Get(c *gem.Context, name string) Session {
+	return c.MustGet(DefaultKey + "/" + name).(Session)
+}
+
+// NewID returns a random session ID suitable for use as a storage key.
+func NewID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("sessions: generate id: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ValidID reports whether id has the format produced by NewID.
+// Stores should reject any other value before using it as a key.
+func ValidID(id string) bool {
+	if len(id) != 43 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+type session struct {
+	name    string
+	store   Store
+	opts    Options
+	request *http.Request
+	writer  http.ResponseWriter
+
+	id     string
+	values map[string]any
+	// stale holds the IDs discarded by Regenerate and Destroy, deleted from the store on save.
+	stale []string
+
+	loaded    bool
+	dirty     bool
+	destroyed bool
+}
+
+func (s *session) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	if cookie, err := s.request.Cookie(s.name); err == nil {
+		if id, values, err := s.store.Load(cookie.Value); err == nil {
+			s.id, s.values = id, values
+		}
+	}
+	if s.values == nil {
+		s.values = make(map[string]any)
+	}
+}
+
+func (s *session) ID() string {
+	s.load()
+	if s.id == "" {
+		s.id = NewID()
+		s.dirty = true
+	}
+	return s.id
+}
+
+func (s *session) Get(key string) (any, bool) {
+	s.load()
+	val, ok := s.values[key]
+	return val, ok
+}
+
+func (s *session) Set(key string, val any) {
+	s.load()
+	s.values[key] = val
+	s.touch()
+}
+
+func (s *session) Delete(key string) {
+	s.load()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.touch()
+	}
+}
+
+func (s *session) Flash(val any) {
+	s.load()
+	flashes, _ := s.values[flashKey].([]any)
+	s.values[flashKey] = append(flashes, val)
+	s.touch()
+}
+
+func (s *session) Flashes() []any {
+	s.load()
+	flashes, ok := s.values[flashKey].([]any)
+	if ok {
+		delete(s.values, flashKey)
+		s.touch()
+	}
+	return flashes
+}
+
+func (s *session) Regenerate() error {
+	s.load()
+	s.discardID()
+	s.id = NewID()
+	s.touch()
+	return nil
+}
+
+func (s *session) Destroy() error {
+	s.load()
+	s.discardID()
+	s.values = make(map[string]any)
+	s.destroyed = true
+	s.dirty = true
+	return nil
+}
+
+// touch marks the session as modified, reviving it after Destroy.
+func (s *session) touch() {
+	s.dirty = true
+	s.destroyed = false
+}
+
+// discardID queues the current ID for deletion so that it can never be presented again.
+func (s *session) discardID() {
+	if s.id != "" {
+		s.stale = append(s.stale, s.id)
+		s.id = ""
+	}
+}
+
+func (s *session) Save() error {
+	if !s.dirty {
+		return nil
+	}
+	s.dirty = false
+
+	for len(s.stale) > 0 {
+		if err := s.store.Delete(s.stale[0]); err != nil {
+			return err
+		}
+		s.stale = s.stale[1:]
+	}
+
+	if s.destroyed {
+		http.SetCookie(s.writer, s.cookie("", -1))
+		return nil
+	}
+
+	maxAge := s.opts.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultMaxAge
+	}
+	value, err := s.store.Save(s.ID(), s.values, maxAge)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(s.writer, s.cookie(value, int(s.opts.MaxAge/time.Second)))
+	return nil
+}
+
+// autoSave saves the session before the response header leaves,
+// reporting failures the handler had no chance to observe.
+func (s *session) autoSave() {
+	if err := s.Save(); err != nil {
+		fmt.Fprintf(gem.DefaultErrWriter, "[SESSIONS] save session %q: %v\n", s.name, err)
+	}
+}
+
+func (s *session) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     s.name,
+		Value:    value,
+		Path:     s.opts.Path,
+		Domain:   s.opts.Domain,
+		MaxAge:   maxAge,
+		Secure:   s.opts.Secure,
+		HttpOnly: s.opts.HttpOnly,
+		SameSite: s.opts.SameSite,
+	}
+}
+
+// sessionWriter saves the session right before the response header is written,
+// since Set-Cookie can not be added afterwards.
+type sessionWriter struct {
+	gem.ResponseWriter
+	s *session
+}
+
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *sessionWriter) Write(data []byte) (int, error) {
+	w.before()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *sessionWriter) WriteString(s string) (int, error) {
+	w.before()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *sessionWriter) WriteHeaderNow() {
+	w.before()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *sessionWriter) Flush() {
+	w.before()
+	w.ResponseWriter.Flush()
+}
+
+func (w *sessionWriter) before() {
+	if !w.ResponseWriter.Written() {
+		w.s.autoSave()
+	}
+}
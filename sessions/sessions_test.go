@@ -0,0 +1,149 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crazyfrankie/gem"
+)
+
+func newServer(store Store) *gem.Server {
+	s := gem.New()
+	s.Use(Sessions("sid", store, nil))
+	s.GET("/set", func(c *gem.Context) {
+		Default(c).Set("user", "frank")
+		Default(c).Flash("welcome")
+		c.String(http.StatusOK, "ok")
+	})
+	s.GET("/get", func(c *gem.Context) {
+		user, _ := Default(c).Get("user")
+		flashes := Default(c).Flashes()
+		c.String(http.StatusOK, "%v %v", user, flashes)
+	})
+	s.GET("/login", func(c *gem.Context) {
+		if err := Default(c).Regenerate(); err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	s.GET("/logout", func(c *gem.Context) {
+		_ = Default(c).Destroy()
+		c.String(http.StatusOK, "ok")
+	})
+	return s
+}
+
+func do(t *testing.T, h http.Handler, path string, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "sid" {
+			return w, c
+		}
+	}
+	return w, nil
+}
+
+func testStore(t *testing.T, store Store) {
+	s := newServer(store)
+
+	_, cookie := do(t, s, "/set", nil)
+	if cookie == nil || cookie.Value == "" {
+		t.Fatal("expected a session cookie")
+	}
+
+	w, updated := do(t, s, "/get", cookie)
+	if got := w.Body.String(); got != "frank [welcome]" {
+		t.Fatalf("unexpected body %q", got)
+	}
+	if updated != nil {
+		// Cookie stores carry the values themselves, so the consumed flash changes the cookie.
+		cookie = updated
+	}
+	w, _ = do(t, s, "/get", cookie)
+	if got := w.Body.String(); got != "frank []" {
+		t.Fatalf("flash should only be returned once, got %q", got)
+	}
+
+	_, rotated := do(t, s, "/login", cookie)
+	if rotated == nil || rotated.Value == cookie.Value {
+		t.Fatal("expected regenerate to issue a new cookie")
+	}
+	w, _ = do(t, s, "/get", rotated)
+	if got := w.Body.String(); got != "frank []" {
+		t.Fatalf("values should survive regenerate, got %q", got)
+	}
+
+	_, expired := do(t, s, "/logout", rotated)
+	if expired == nil || expired.MaxAge >= 0 {
+		t.Fatal("expected destroy to expire the cookie")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(0)
+	defer store.Close()
+	testStore(t, store)
+
+	// The ID discarded by Regenerate and Destroy must not be accepted again.
+	if n := store.Len(); n != 0 {
+		t.Fatalf("expected stale sessions to be deleted, %d left", n)
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	store := NewMemoryStore(0)
+	defer store.Close()
+
+	id := NewID()
+	if _, err := store.Save(id, map[string]any{"k": 1}, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	store.Evict()
+	if store.Len() != 0 {
+		t.Fatal("expected expired session to be evicted")
+	}
+	if _, _, err := store.Load(id); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	testStore(t, store)
+
+	if _, _, err := store.Load("../../etc/passwd"); err != ErrInvalidID {
+		t.Fatalf("expected ErrInvalidID, got %v", err)
+	}
+}
+
+func TestCookieStore(t *testing.T) {
+	store, err := NewCookieStore([]byte("hash-key"), []byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, store)
+
+	value, err := store.Save(NewID(), map[string]any{"role": "user"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := []byte(value)
+	tampered[3] ^= 1
+	if _, _, err := store.Load(string(tampered)); err == nil {
+		t.Fatal("expected tampered cookie to be rejected")
+	}
+}
@@ -41,36 +41,59 @@ type TraceBuilder struct {
 // net.protocol.name       string Note: not set if the value is "http".
 // net.protocol.version    string
 // http.target             string Note: doesn't include the query parameter.
+//
+// The host and scheme are those the request was received with, see HTTPContext
+// for the ones resolved through trusted proxies.
 func (t TraceBuilder) HTTPRequset(server string, r *http.Request) []attribute.KeyValue {
-	// http Method, scheme, and host name.
+	return t.requestAttrs(server, r, r.Host, r.TLS != nil, "")
+}
+
+// HTTPContext returns the attributes of HTTPRequset and http.client_ip for the request of c.
+// The host, scheme and client IP are resolved through Context.Host, Context.Scheme and
+// Context.ClientIP, so forwarding headers are only honored from trusted proxies.
+func (t TraceBuilder) HTTPContext(server string, c *Context) []attribute.KeyValue {
+	return t.requestAttrs(server, c.Request, c.Host(), c.Scheme() == "https", c.ClientIP())
+}
+
+func (t TraceBuilder) requestAttrs(server string, r *http.Request, requestHost string, https bool, clientIP string) []attribute.KeyValue {
+	if t.httpconv == nil {
+		t.httpconv = traceconv.NewHttpConv()
+	}
+	// http Method, scheme and host name.
 	count := 3
 
-	var host string
-	var port int
-	if server == "" {
-		host, port = t.httpconv.SplitHostPort(r.Host)
-	} else {
+	host, port := t.httpconv.SplitHostPort(requestHost)
+	if server != "" {
 		host = server
-		_, port = t.httpconv.SplitHostPort(r.Host)
 	}
-	count++
+	if port > 0 {
+		count++
+	}
+	if clientIP != "" {
+		count++
+	}
 
 	protos := t.httpconv.NetConv.NetProtocol(r.Proto)
-	if protos[0] != "" && protos[1] != "" {
+	if len(protos) == 2 && protos[0] != "" && protos[1] != "" {
 		count++
 	}
 
 	attrs := make([]attribute.KeyValue, 0, count)
 
 	attrs = append(attrs, t.httpconv.HTTPMethod(r.Method))
-	attrs = append(attrs, t.httpconv.HTTPScheme(r.URL.Scheme == "http"))
+	attrs = append(attrs, t.httpconv.HTTPScheme(https))
 	attrs = append(attrs, t.httpconv.NetConv.NetHostName(host))
+	if clientIP != "" {
+		attrs = append(attrs, attribute.String("http.client_ip", clientIP))
+	}
 
 	if port > 0 {
 		attrs = append(attrs, attribute.Int("net.host.port", port))
 	}
 
-	attrs = append(attrs, attribute.String("net.protocol.version", protos[1]))
+	if len(protos) == 2 && protos[0] != "" && protos[1] != "" {
+		attrs = append(attrs, attribute.String("net.protocol.version", protos[1]))
+	}
 
 	return attrs
 }
@@ -113,7 +136,7 @@ func (t TraceBuilder) Trace(service string) HandlerFunc {
 
 		ctx := t.Propagations.Extract(oldCtx, propagation.HeaderCarrier(c.Request.Header))
 		opts := []trace.SpanStartOption{
-			trace.WithAttributes(t.HTTPRequset(service, c.Request)...),
+			trace.WithAttributes(t.HTTPContext(service, c)...),
 			trace.WithAttributes(semconv.HTTPRoute(c.FullPath())),
 			trace.WithSpanKind(trace.SpanKindServer),
 		}
@@ -125,7 +148,7 @@ func (t TraceBuilder) Trace(service string) HandlerFunc {
 			spanName = c.FullPath()
 		}
 
-		ctx, span := tracer.Start(c, spanName, opts...)
+		ctx, span := tracer.Start(ctx, spanName, opts...)
 		defer span.End()
 
 		// Set the updated ctx back to the request context to ensure that the traceconv information
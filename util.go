@@ -4,6 +4,8 @@ import (
 	"path"
 	"reflect"
 	"runtime"
+	"strings"
+	"unicode/utf8"
 )
 
 // H is a shortcut for map[string]any
@@ -37,3 +39,61 @@ func assert(guard bool, text string) {
 func nameOfFunction(f any) string {
 	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
 }
+
+func filterFlags(content string) string {
+	for i, char := range content {
+		if char == ' ' || char == ';' {
+			return content[:i]
+		}
+	}
+	return content
+}
+
+// contentDisposition formats a Content-Disposition header value as described in RFC 6266.
+// Filenames that are not plain ASCII get a sanitized filename fallback for old clients
+// and the exact name in the RFC 5987 encoded filename* parameter.
+func contentDisposition(dispositionType, filename string) string {
+	fallback, plain := asciiFilename(filename)
+	v := dispositionType + `; filename="` + fallback + `"`
+	if !plain {
+		v += "; filename*=UTF-8''" + encodeRFC5987(filename)
+	}
+	return v
+}
+
+// asciiFilename returns filename with the characters that can not appear in a quoted-string
+// replaced, and whether no replacement was necessary.
+func asciiFilename(filename string) (string, bool) {
+	var b strings.Builder
+	plain := true
+	for _, r := range filename {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f || r >= utf8.RuneSelf || r == '%':
+			b.WriteByte('_')
+			plain = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), plain
+}
+
+// encodeRFC5987 percent-encodes every byte of s that is not an attr-char.
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"sync"
+)
+
+// deflateTail is appended to a compressed message before inflating it, see RFC 7692 section 7.2.2.
+// The trailing empty stored block makes the inflater report EOF instead of ErrUnexpectedEOF.
+const deflateTail = "\x00\x00\xff\xff\x01\x00\x00\xff\xff"
+
+var errMessageTooBig = errors.New("websocket: message too big")
+
+var flateWriterPool = sync.Pool{New: func() any {
+	w, _ := flate.NewWriter(nil, flate.BestSpeed)
+	return w
+}}
+
+// compressMessage deflates data as a single message without context takeover.
+func compressMessage(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fw := flateWriterPool.Get().(*flate.Writer)
+	defer flateWriterPool.Put(fw)
+	fw.Reset(&buf)
+
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+	// Flush ends with the 0x00 0x00 0xff 0xff of an empty stored block, which must be removed.
+	return bytes.TrimSuffix(buf.Bytes(), []byte(deflateTail[:4])), nil
+}
+
+// decompressMessage inflates a message, failing with errMessageTooBig once it grows past limit.
+func decompressMessage(data []byte, limit int64) ([]byte, error) {
+	fr := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader([]byte(deflateTail))))
+	defer fr.Close()
+
+	var r io.Reader = fr
+	if limit > 0 {
+		r = io.LimitReader(fr, limit+1)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(out)) > limit {
+		return nil, errMessageTooBig
+	}
+	return out, nil
+}
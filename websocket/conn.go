@@ -0,0 +1,553 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// The message types are defined in RFC 6455, section 11.8.
+const (
+	// TextMessage denotes a text data message. The text message payload is
+	// interpreted as UTF-8 encoded text data.
+	TextMessage = 1
+
+	// BinaryMessage denotes a binary data message.
+	BinaryMessage = 2
+
+	// CloseMessage denotes a close control message. The optional message
+	// payload contains a numeric code and text. Use the FormatCloseMessage
+	// function to format a close message payload.
+	CloseMessage = 8
+
+	// PingMessage denotes a ping control message. The optional message payload
+	// is UTF-8 encoded text.
+	PingMessage = 9
+
+	// PongMessage denotes a pong control message. The optional message payload
+	// is UTF-8 encoded text.
+	PongMessage = 10
+)
+
+// Close codes defined in RFC 6455, section 11.7.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+	CloseServiceRestart          = 1012
+	CloseTryAgainLater           = 1013
+	CloseTLSHandshake            = 1015
+)
+
+const (
+	continuationFrame = 0
+
+	finalBit = 1 << 7
+	rsv1Bit  = 1 << 6
+	rsv2Bit  = 1 << 5
+	rsv3Bit  = 1 << 4
+	maskBit  = 1 << 7
+
+	maxControlFramePayloadSize = 125
+	maxFrameHeaderSize         = 2 + 8 + 4
+)
+
+var (
+	// ErrCloseSent is returned when the application writes a message to the
+	// connection after sending a close message.
+	ErrCloseSent = errors.New("websocket: close sent")
+
+	errInvalidControlFrame = errors.New("websocket: invalid control frame")
+	errBadMessageType      = errors.New("websocket: bad message type")
+)
+
+// CloseError is returned by ReadMessage when the peer closes the connection or a protocol violation ends it.
+type CloseError struct {
+	// Code is defined in RFC 6455, section 11.7.
+	Code int
+
+	// Text is the optional text payload.
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	s := "websocket: close " + strconv.Itoa(e.Code)
+	if e.Text != "" {
+		s += ": " + e.Text
+	}
+	return s
+}
+
+// IsCloseError returns boolean indicating whether the error is a *CloseError with one of the specified codes.
+func IsCloseError(err error, codes ...int) bool {
+	var e *CloseError
+	if !errors.As(err, &e) {
+		return false
+	}
+	for _, code := range codes {
+		if e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatCloseMessage formats closeCode and text as a WebSocket close message.
+// An empty message is returned for code CloseNoStatusReceived.
+func FormatCloseMessage(closeCode int, text string) []byte {
+	if closeCode == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(closeCode))
+	copy(buf[2:], text)
+	return buf
+}
+
+// Conn represents a server side WebSocket connection.
+//
+// Applications are responsible for ensuring that no more than one goroutine calls the read methods
+// concurrently. The write methods may be called concurrently, they are serialized internally.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	subprotocol string
+
+	// compress is set when permessage-deflate was negotiated.
+	compress      bool
+	writeCompress bool
+
+	writeMu   sync.Mutex
+	closeSent bool
+
+	readLimit int64
+	readErr   error
+
+	handlePing  func(appData string) error
+	handlePong  func(appData string) error
+	handleClose func(code int, text string) error
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, readLimit int64) *Conn {
+	c := &Conn{conn: conn, br: br, readLimit: readLimit}
+	c.SetPingHandler(nil)
+	c.SetPongHandler(nil)
+	c.SetCloseHandler(nil)
+	return c
+}
+
+// Subprotocol returns the negotiated protocol for the connection.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// NetConn returns the underlying connection.
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the read deadline on the underlying network connection.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline on the underlying network connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetReadLimit sets the maximum size in bytes for a message read from the peer,
+// a non-positive limit disables the check.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// EnableWriteCompression enables and disables write compression of subsequent messages.
+// It has no effect unless permessage-deflate was negotiated.
+func (c *Conn) EnableWriteCompression(enable bool) {
+	c.writeMu.Lock()
+	c.writeCompress = enable && c.compress
+	c.writeMu.Unlock()
+}
+
+// SetPingHandler sets the handler for ping messages received from the peer.
+// The default handler answers with a pong carrying the same application data.
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(message string) error {
+			err := c.WriteMessage(PongMessage, []byte(message))
+			if errors.Is(err, ErrCloseSent) {
+				return nil
+			}
+			return err
+		}
+	}
+	c.handlePing = h
+}
+
+// SetPongHandler sets the handler for pong messages received from the peer.
+// The default handler does nothing.
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(string) error { return nil }
+	}
+	c.handlePong = h
+}
+
+// SetCloseHandler sets the handler for close messages received from the peer.
+// The default handler echoes the close code back, completing the closing handshake.
+func (c *Conn) SetCloseHandler(h func(code int, text string) error) {
+	if h == nil {
+		h = func(code int, text string) error {
+			err := c.WriteMessage(CloseMessage, FormatCloseMessage(code, ""))
+			if errors.Is(err, ErrCloseSent) {
+				return nil
+			}
+			return err
+		}
+	}
+	c.handleClose = h
+}
+
+// Close closes the underlying network connection without sending or waiting for a close message.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// WriteClose sends a close message with code and text. The application should keep reading
+// until ReadMessage returns the peer's CloseError and then call Close.
+func (c *Conn) WriteClose(code int, text string) error {
+	return c.WriteMessage(CloseMessage, FormatCloseMessage(code, text))
+}
+
+// WriteMessage writes a message of the given type as a single frame.
+// Control messages must not carry more than 125 bytes.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if len(data) > maxControlFramePayloadSize {
+			return errInvalidControlFrame
+		}
+	default:
+		return errBadMessageType
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	var b0 byte = finalBit | byte(messageType)
+	if c.writeCompress && (messageType == TextMessage || messageType == BinaryMessage) {
+		compressed, err := compressMessage(data)
+		if err != nil {
+			return err
+		}
+		data = compressed
+		b0 |= rsv1Bit
+	}
+
+	frame := make([]byte, 0, maxFrameHeaderSize+len(data))
+	frame = append(frame, b0)
+	switch n := len(data); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 65535:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, data...)
+
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// ReadMessage reads the next complete data message, answering control messages on the way.
+// messageType is either TextMessage or BinaryMessage. Once the connection is closed, by the peer
+// or due to a protocol violation, ReadMessage keeps returning the same error, usually a *CloseError.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, p, err = c.readMessage()
+	if err != nil {
+		c.readErr = err
+	}
+	return messageType, p, err
+}
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	var (
+		messageType int
+		compressed  bool
+		message     []byte
+	)
+	for {
+		f, err := c.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if f.opcode >= CloseMessage {
+			if err := c.handleControl(f); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		if f.opcode == continuationFrame {
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "continuation frame without a message in progress")
+			}
+			if f.rsv1 {
+				return 0, nil, c.fail(CloseProtocolError, "RSV1 set on continuation frame")
+			}
+		} else {
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "new message started before the final frame of the previous one")
+			}
+			if f.rsv1 && !c.compress {
+				return 0, nil, c.fail(CloseProtocolError, "RSV1 set without negotiated extension")
+			}
+			messageType, compressed = f.opcode, f.rsv1
+		}
+
+		message = append(message, f.payload...)
+		// Validate uncompressed text as early as possible, so invalid input fails fast.
+		if messageType == TextMessage && !compressed && !validUTF8Prefix(message, f.fin) {
+			return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid UTF-8 in text message")
+		}
+		if !f.fin {
+			continue
+		}
+
+		if compressed {
+			if message, err = decompressMessage(message, c.readLimit); err != nil {
+				if errors.Is(err, errMessageTooBig) {
+					return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+				}
+				return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid compressed data")
+			}
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid UTF-8 in text message")
+			}
+		}
+		if message == nil {
+			message = []byte{}
+		}
+		return messageType, message, nil
+	}
+}
+
+func (c *Conn) handleControl(f frame) error {
+	switch f.opcode {
+	case PingMessage:
+		return c.handlePing(string(f.payload))
+	case PongMessage:
+		return c.handlePong(string(f.payload))
+	}
+
+	code, text := CloseNoStatusReceived, ""
+	switch {
+	case len(f.payload) == 1:
+		return c.fail(CloseProtocolError, "close frame payload of one byte")
+	case len(f.payload) >= 2:
+		code = int(binary.BigEndian.Uint16(f.payload))
+		text = string(f.payload[2:])
+		if !validCloseCode(code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(text) {
+			return c.fail(CloseInvalidFramePayloadData, "invalid UTF-8 in close frame")
+		}
+	}
+	if err := c.handleClose(code, text); err != nil {
+		return err
+	}
+	return &CloseError{Code: code, Text: text}
+}
+
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  int
+	payload []byte
+}
+
+// readFrame reads and unmasks a single frame. buffered is the size of the message read so far,
+// used to enforce the read limit before the payload is allocated.
+func (c *Conn) readFrame(buffered int64) (frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return frame{}, c.abnormal(err)
+	}
+
+	f := frame{
+		fin:    header[0]&finalBit != 0,
+		rsv1:   header[0]&rsv1Bit != 0,
+		opcode: int(header[0] & 0xf),
+	}
+	if header[0]&(rsv2Bit|rsv3Bit) != 0 {
+		return frame{}, c.fail(CloseProtocolError, "unexpected reserved bits")
+	}
+	switch f.opcode {
+	case continuationFrame, TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if !f.fin {
+			return frame{}, c.fail(CloseProtocolError, "fragmented control frame")
+		}
+		if f.rsv1 {
+			return frame{}, c.fail(CloseProtocolError, "RSV1 set on control frame")
+		}
+	default:
+		return frame{}, c.fail(CloseProtocolError, "unknown opcode "+strconv.Itoa(f.opcode))
+	}
+	if header[1]&maskBit == 0 {
+		return frame{}, c.fail(CloseProtocolError, "client frame is not masked")
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return frame{}, c.abnormal(err)
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return frame{}, c.abnormal(err)
+		}
+		n := binary.BigEndian.Uint64(ext[:])
+		if n>>63 != 0 {
+			return frame{}, c.fail(CloseProtocolError, "invalid payload length")
+		}
+		length = int64(n)
+	}
+	if f.opcode >= CloseMessage {
+		if length > maxControlFramePayloadSize {
+			return frame{}, c.fail(CloseProtocolError, "control frame payload too large")
+		}
+	} else if c.readLimit > 0 && buffered+length > c.readLimit {
+		return frame{}, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return frame{}, c.abnormal(err)
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return frame{}, c.abnormal(err)
+	}
+	for i := range f.payload {
+		f.payload[i] ^= mask[i&3]
+	}
+	return f, nil
+}
+
+// fail sends a close message for a protocol violation and closes the connection.
+func (c *Conn) fail(code int, text string) error {
+	_ = c.WriteMessage(CloseMessage, FormatCloseMessage(code, ""))
+	_ = c.conn.Close()
+	return &CloseError{Code: code, Text: text}
+}
+
+// abnormal converts a read failure of the underlying connection into a CloseError.
+func (c *Conn) abnormal(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &CloseError{Code: CloseAbnormalClosure, Text: io.ErrUnexpectedEOF.Error()}
+	}
+	return err
+}
+
+// validCloseCode reports whether code may be sent in a close frame, see RFC 6455 section 7.4.
+func validCloseCode(code int) bool {
+	switch code {
+	case CloseNormalClosure, CloseGoingAway, CloseProtocolError, CloseUnsupportedData,
+		CloseInvalidFramePayloadData, ClosePolicyViolation, CloseMessageTooBig,
+		CloseMandatoryExtension, CloseInternalServerErr, CloseServiceRestart, CloseTryAgainLater:
+		return true
+	}
+	return code >= 3000 && code <= 4999
+}
+
+// validUTF8Prefix reports whether b is valid UTF-8, allowing an incomplete
+// trailing sequence when more fragments are still to come.
+func validUTF8Prefix(b []byte, final bool) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size <= 1 {
+			return !final && incompleteRune(b)
+		}
+		b = b[size:]
+	}
+	return true
+}
+
+// incompleteRune reports whether b is the start of a valid multi-byte sequence that was cut short.
+func incompleteRune(b []byte) bool {
+	var need int
+	lo, hi := byte(0x80), byte(0xbf)
+	switch c := b[0]; {
+	case c >= 0xc2 && c <= 0xdf:
+		need = 2
+	case c == 0xe0:
+		need, lo = 3, 0xa0
+	case c == 0xed:
+		need, hi = 3, 0x9f
+	case c >= 0xe1 && c <= 0xef:
+		need = 3
+	case c == 0xf0:
+		need, lo = 4, 0x90
+	case c == 0xf4:
+		need, hi = 4, 0x8f
+	case c >= 0xf1 && c <= 0xf3:
+		need = 4
+	default:
+		return false
+	}
+	if len(b) >= need {
+		return false
+	}
+	for i := 1; i < len(b); i++ {
+		if b[i] < lo || b[i] > hi {
+			return false
+		}
+		lo, hi = 0x80, 0xbf
+	}
+	return true
+}
@@ -0,0 +1,392 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clientFrame encodes a masked client frame.
+func clientFrame(b0 byte, payload []byte) []byte {
+	mask := [4]byte{0x37, 0xfa, 0x21, 0x3d}
+	buf := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 65535:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	buf = append(buf, mask[:]...)
+	for i, b := range payload {
+		buf = append(buf, b^mask[i&3])
+	}
+	return buf
+}
+
+type serverFrame struct {
+	b0      byte
+	payload []byte
+}
+
+func readServerFrame(t *testing.T, r *bufio.Reader) serverFrame {
+	t.Helper()
+	var h [2]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		t.Fatalf("read frame header: %v", err)
+	}
+	n := int(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		n = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("read frame payload: %v", err)
+	}
+	return serverFrame{b0: h[0], payload: payload}
+}
+
+// echo runs an echo server on one end of a pipe and returns the client end.
+func echo(t *testing.T, compress bool, limit int64) (net.Conn, *bufio.Reader) {
+	server, client := net.Pipe()
+	c := newConn(server, bufio.NewReader(server), limit)
+	c.compress, c.writeCompress = compress, compress
+	go func() {
+		defer c.Close()
+		for {
+			mt, p, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := c.WriteMessage(mt, p); err != nil {
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() { client.Close() })
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	return client, bufio.NewReader(client)
+}
+
+func send(conn net.Conn, frames ...[]byte) {
+	go func() {
+		for _, f := range frames {
+			if _, err := conn.Write(f); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func expectClose(t *testing.T, r *bufio.Reader, code int) {
+	t.Helper()
+	f := readServerFrame(t, r)
+	if f.b0&0xf != CloseMessage {
+		t.Fatalf("expected close frame, got opcode %d", f.b0&0xf)
+	}
+	if len(f.payload) < 2 {
+		t.Fatalf("expected close code %d, got empty close", code)
+	}
+	if got := int(binary.BigEndian.Uint16(f.payload)); got != code {
+		t.Fatalf("expected close code %d, got %d", code, got)
+	}
+}
+
+func expectMessage(t *testing.T, r *bufio.Reader, opcode int, payload string) {
+	t.Helper()
+	f := readServerFrame(t, r)
+	if int(f.b0&0xf) != opcode || string(f.payload) != payload {
+		t.Fatalf("expected opcode %d %q, got opcode %d %q", opcode, payload, f.b0&0xf, f.payload)
+	}
+}
+
+func TestFraming(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames [][]byte
+		check  func(t *testing.T, r *bufio.Reader)
+	}{
+		{
+			// Autobahn 1.1.x / 1.2.x
+			name:   "text and binary echo",
+			frames: [][]byte{clientFrame(finalBit|TextMessage, []byte("hello")), clientFrame(finalBit|BinaryMessage, bytes.Repeat([]byte{0xfe}, 1400))},
+			check: func(t *testing.T, r *bufio.Reader) {
+				expectMessage(t, r, TextMessage, "hello")
+				expectMessage(t, r, BinaryMessage, string(bytes.Repeat([]byte{0xfe}, 1400)))
+			},
+		},
+		{
+			// Autobahn 2.2: ping answered with pong carrying the same payload
+			name:   "ping",
+			frames: [][]byte{clientFrame(finalBit|PingMessage, []byte("ping"))},
+			check: func(t *testing.T, r *bufio.Reader) {
+				expectMessage(t, r, PongMessage, "ping")
+			},
+		},
+		{
+			// Autobahn 2.5: ping payload longer than 125 bytes
+			name:   "oversized ping",
+			frames: [][]byte{clientFrame(finalBit|PingMessage, bytes.Repeat([]byte("x"), 126))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseProtocolError) },
+		},
+		{
+			// Autobahn 3.1: reserved bit without negotiated extension
+			name:   "rsv2 set",
+			frames: [][]byte{clientFrame(finalBit|rsv2Bit|TextMessage, []byte("x"))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseProtocolError) },
+		},
+		{
+			name:   "rsv1 without compression",
+			frames: [][]byte{clientFrame(finalBit|rsv1Bit|TextMessage, []byte("x"))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseProtocolError) },
+		},
+		{
+			// Autobahn 4.1.1: reserved non-control opcode
+			name:   "reserved opcode",
+			frames: [][]byte{clientFrame(finalBit|3, nil)},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseProtocolError) },
+		},
+		{
+			// Autobahn 5.3 / 5.6: fragmented text with an interleaved ping
+			name: "fragmented text with ping",
+			frames: [][]byte{
+				clientFrame(TextMessage, []byte("frag")),
+				clientFrame(finalBit|PingMessage, []byte("p")),
+				clientFrame(continuationFrame, []byte("men")),
+				clientFrame(finalBit|continuationFrame, []byte("ted")),
+			},
+			check: func(t *testing.T, r *bufio.Reader) {
+				expectMessage(t, r, PongMessage, "p")
+				expectMessage(t, r, TextMessage, "fragmented")
+			},
+		},
+		{
+			// Autobahn 5.1: fragmented ping
+			name:   "fragmented control frame",
+			frames: [][]byte{clientFrame(PingMessage, []byte("a")), clientFrame(finalBit|continuationFrame, []byte("b"))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseProtocolError) },
+		},
+		{
+			// Autobahn 5.9: continuation without a message in progress
+			name:   "orphan continuation",
+			frames: [][]byte{clientFrame(finalBit|continuationFrame, []byte("x"))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseProtocolError) },
+		},
+		{
+			name:   "new message inside fragmented one",
+			frames: [][]byte{clientFrame(TextMessage, []byte("a")), clientFrame(finalBit|TextMessage, []byte("b"))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseProtocolError) },
+		},
+		{
+			// Autobahn 6.x: UTF-8 split across fragments is valid
+			name: "utf8 split across fragments",
+			frames: [][]byte{
+				clientFrame(TextMessage, []byte("κό\xcf")),
+				clientFrame(finalBit|continuationFrame, []byte("\x83με")),
+			},
+			check: func(t *testing.T, r *bufio.Reader) { expectMessage(t, r, TextMessage, "κόσμε") },
+		},
+		{
+			// Autobahn 6.3.1: invalid UTF-8
+			name:   "invalid utf8",
+			frames: [][]byte{clientFrame(finalBit|TextMessage, []byte("\xce\xba\xe1\xbd\xb9\xcf\x83\xce\xbc\xce\xb5\xed\xa0\x80edited"))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseInvalidFramePayloadData) },
+		},
+		{
+			// Autobahn 6.4.x: fail fast on invalid UTF-8 in an unfinished message
+			name:   "invalid utf8 fail fast",
+			frames: [][]byte{clientFrame(TextMessage, []byte("ok\xf4\x90"))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseInvalidFramePayloadData) },
+		},
+		{
+			// Autobahn 7.3.1: close with empty payload
+			name:   "empty close",
+			frames: [][]byte{clientFrame(finalBit|CloseMessage, nil)},
+			check: func(t *testing.T, r *bufio.Reader) {
+				f := readServerFrame(t, r)
+				if f.b0&0xf != CloseMessage || len(f.payload) != 0 {
+					t.Fatalf("expected empty close, got opcode %d %q", f.b0&0xf, f.payload)
+				}
+			},
+		},
+		{
+			// Autobahn 7.3.2: close payload of one byte
+			name:   "one byte close",
+			frames: [][]byte{clientFrame(finalBit|CloseMessage, []byte{0x03})},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseProtocolError) },
+		},
+		{
+			// Autobahn 7.7.x: valid close codes are echoed
+			name:   "normal close",
+			frames: [][]byte{clientFrame(finalBit|CloseMessage, FormatCloseMessage(CloseNormalClosure, "bye"))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseNormalClosure) },
+		},
+		{
+			// Autobahn 7.9.x: invalid close codes
+			name:   "invalid close code",
+			frames: [][]byte{clientFrame(finalBit|CloseMessage, FormatCloseMessage(1004, ""))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseProtocolError) },
+		},
+		{
+			// Autobahn 7.5.1: invalid UTF-8 in close reason
+			name:   "invalid close reason",
+			frames: [][]byte{clientFrame(finalBit|CloseMessage, FormatCloseMessage(CloseNormalClosure, "\xff"))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseInvalidFramePayloadData) },
+		},
+		{
+			name:   "unmasked frame",
+			frames: [][]byte{{finalBit | TextMessage, 1, 'x'}},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseProtocolError) },
+		},
+		{
+			name:   "message too big",
+			frames: [][]byte{clientFrame(TextMessage, bytes.Repeat([]byte("a"), 1000)), clientFrame(finalBit|continuationFrame, bytes.Repeat([]byte("a"), 1000))},
+			check:  func(t *testing.T, r *bufio.Reader) { expectClose(t, r, CloseMessageTooBig) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, r := echo(t, false, 1500)
+			send(conn, tt.frames...)
+			tt.check(t, r)
+		})
+	}
+}
+
+func TestCompression(t *testing.T) {
+	conn, r := echo(t, true, 1<<20)
+
+	message := strings.Repeat("compress me ", 100)
+	compressed, err := compressMessage([]byte(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	send(conn, clientFrame(finalBit|rsv1Bit|TextMessage, compressed))
+
+	f := readServerFrame(t, r)
+	if f.b0&rsv1Bit == 0 {
+		t.Fatal("expected compressed echo")
+	}
+	got, err := decompressMessage(f.payload, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != message {
+		t.Fatalf("unexpected echo %q", got)
+	}
+}
+
+func TestDecompressionLimit(t *testing.T) {
+	conn, r := echo(t, true, 1024)
+
+	compressed, err := compressMessage(bytes.Repeat([]byte{0}, 1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	send(conn, clientFrame(finalBit|rsv1Bit|BinaryMessage, compressed))
+	expectClose(t, r, CloseMessageTooBig)
+}
+
+func TestUpgrade(t *testing.T) {
+	upgrader := &Upgrader{Subprotocols: []string{"chat"}, EnableCompression: true}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		mt, p, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.EnableWriteCompression(false)
+		_ = c.WriteMessage(mt, append([]byte(c.Subprotocol()+":"), p...))
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := "GET / HTTP/1.1\r\nHost: " + srv.Listener.Addr().String() + "\r\n" +
+		"Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Protocol: superchat, chat\r\n" +
+		"Sec-WebSocket-Extensions: permessage-deflate; client_max_window_bits\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+	// Sample handshake of RFC 6455 section 1.3.
+	if got := resp.Header.Get("Sec-Websocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %q", got)
+	}
+	if got := resp.Header.Get("Sec-Websocket-Protocol"); got != "chat" {
+		t.Fatalf("unexpected subprotocol %q", got)
+	}
+	if got := resp.Header.Get("Sec-Websocket-Extensions"); !strings.HasPrefix(got, "permessage-deflate") {
+		t.Fatalf("expected permessage-deflate, got %q", got)
+	}
+
+	if _, err := conn.Write(clientFrame(finalBit|TextMessage, []byte("hi"))); err != nil {
+		t.Fatal(err)
+	}
+	expectMessage(t, br, TextMessage, "chat:hi")
+}
+
+func TestUpgradeRejectsBadHandshake(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		status int
+	}{
+		{"missing upgrade", map[string]string{"Connection": "Upgrade", "Sec-Websocket-Version": "13", "Sec-Websocket-Key": "dGhlIHNhbXBsZSBub25jZQ=="}, http.StatusBadRequest},
+		{"bad version", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-Websocket-Version": "8", "Sec-Websocket-Key": "dGhlIHNhbXBsZSBub25jZQ=="}, http.StatusUpgradeRequired},
+		{"bad key", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-Websocket-Version": "13", "Sec-Websocket-Key": "short"}, http.StatusBadRequest},
+		{"cross origin", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-Websocket-Version": "13", "Sec-Websocket-Key": "dGhlIHNhbXBsZSBub25jZQ==", "Origin": "https://evil.example"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			if _, err := DefaultUpgrader.Upgrade(w, req, nil); err == nil {
+				t.Fatal("expected handshake error")
+			}
+			if w.Code != tt.status {
+				t.Fatalf("expected %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
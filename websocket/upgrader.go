@@ -0,0 +1,242 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// keyGUID is the fixed GUID of RFC 6455 section 1.3 appended to Sec-WebSocket-Key.
+const keyGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// DefaultMaxMessageSize is the read limit used when Upgrader.MaxMessageSize is zero.
+const DefaultMaxMessageSize = 16 << 20
+
+// HandshakeError describes an error with the handshake from the peer.
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e HandshakeError) Error() string {
+	return "websocket: " + e.Message
+}
+
+// Upgrader specifies parameters for upgrading an HTTP connection to a WebSocket connection.
+// It is safe to call Upgrade concurrently.
+type Upgrader struct {
+	// HandshakeTimeout bounds writing the handshake response, zero means no limit.
+	HandshakeTimeout time.Duration
+
+	// Subprotocols lists the server's supported protocols in order of preference.
+	// The first one also requested by the client is selected.
+	Subprotocols []string
+
+	// CheckOrigin returns true if the request Origin header is acceptable.
+	// If nil, requests whose Origin host differs from the Host header are rejected with 403.
+	CheckOrigin func(r *http.Request) bool
+
+	// EnableCompression negotiates permessage-deflate (RFC 7692) when the client offers it.
+	EnableCompression bool
+
+	// MaxMessageSize is the largest message, after decompression, that the connection accepts.
+	// Larger messages close the connection with CloseMessageTooBig.
+	// Zero means DefaultMaxMessageSize, a negative value disables the limit.
+	MaxMessageSize int64
+}
+
+// DefaultUpgrader is the Upgrader used by Upgrade.
+var DefaultUpgrader = &Upgrader{}
+
+// Upgrade upgrades the HTTP server connection with DefaultUpgrader.
+func Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	return DefaultUpgrader.Upgrade(w, r, responseHeader)
+}
+
+// Upgrade validates the opening handshake of r and upgrades the connection to the WebSocket protocol.
+// On failure it replies with an HTTP error and returns a HandshakeError.
+// responseHeader is included in the 101 response, use it to set cookies;
+// Sec-WebSocket-Protocol must be configured through Subprotocols instead.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return u.fail(w, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") {
+		return u.fail(w, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return u.fail(w, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return u.fail(w, http.StatusUpgradeRequired, "unsupported version: 13 not found in 'Sec-Websocket-Version' header")
+	}
+	key := r.Header.Get("Sec-Websocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return u.fail(w, http.StatusBadRequest, "'Sec-WebSocket-Key' header must be Base64 encoded value of 16-byte in length")
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		return u.fail(w, http.StatusForbidden, "request origin not allowed by Upgrader.CheckOrigin")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+	compress := u.EnableCompression && negotiateDeflate(r.Header)
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return u.fail(w, http.StatusInternalServerError, "response does not implement http.Hijacker")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	buf.WriteString(computeAcceptKey(key))
+	buf.WriteString("\r\n")
+	if subprotocol != "" {
+		buf.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		buf.WriteString("Sec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	for k, vs := range responseHeader {
+		if http.CanonicalHeaderKey(k) == "Sec-Websocket-Protocol" {
+			continue
+		}
+		for _, v := range vs {
+			buf.WriteString(k + ": " + strings.NewReplacer("\r", "", "\n", "").Replace(v) + "\r\n")
+		}
+	}
+	buf.WriteString("\r\n")
+
+	if u.HandshakeTimeout > 0 {
+		_ = netConn.SetWriteDeadline(time.Now().Add(u.HandshakeTimeout))
+	}
+	if _, err := netConn.Write(buf.Bytes()); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if u.HandshakeTimeout > 0 {
+		_ = netConn.SetWriteDeadline(time.Time{})
+	}
+
+	maxSize := u.MaxMessageSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxMessageSize
+	}
+	br := brw.Reader
+	if br == nil {
+		br = bufio.NewReader(netConn)
+	}
+	c := newConn(netConn, br, maxSize)
+	c.subprotocol = subprotocol
+	c.compress = compress
+	c.writeCompress = compress
+	return c, nil
+}
+
+func (u *Upgrader) fail(w http.ResponseWriter, status int, reason string) (*Conn, error) {
+	err := HandshakeError{Status: status, Message: reason}
+	w.Header().Set("Sec-Websocket-Version", "13")
+	http.Error(w, http.StatusText(status), status)
+	return nil, err
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	requested := Subprotocols(r)
+	for _, server := range u.Subprotocols {
+		for _, client := range requested {
+			if client == server {
+				return client
+			}
+		}
+	}
+	return ""
+}
+
+// Subprotocols returns the subprotocols requested by the client in the Sec-Websocket-Protocol header.
+func Subprotocols(r *http.Request) []string {
+	var protocols []string
+	for _, v := range r.Header.Values("Sec-Websocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				protocols = append(protocols, p)
+			}
+		}
+	}
+	return protocols
+}
+
+// IsWebSocketUpgrade returns true if the client requested upgrade to the WebSocket protocol.
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key))
+	h.Write([]byte(keyGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// headerContainsToken reports whether the comma separated header name lists token.
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, v := range header.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// negotiateDeflate reports whether the client offered a permessage-deflate configuration
+// the server can accept. The server always answers with no context takeover in both
+// directions, so offers that restrict the server window below the 32K flate uses are declined.
+func negotiateDeflate(header http.Header) bool {
+	for _, v := range header.Values("Sec-Websocket-Extensions") {
+		for _, offer := range strings.Split(v, ",") {
+			params := strings.Split(offer, ";")
+			if !strings.EqualFold(strings.TrimSpace(params[0]), "permessage-deflate") {
+				continue
+			}
+			ok := true
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				case "server_max_window_bits":
+					ok = ok && strings.Trim(strings.TrimSpace(value), `"`) == "15"
+				default:
+					ok = false
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}